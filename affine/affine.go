@@ -68,19 +68,41 @@ func (a *Affine) Multiply(x float64, y float64) (float64, float64) {
 func (a *Affine) Scale(x float64, y float64) *Affine {
 	return &Affine{
 		A: a.A * x,
-		B: a.B,
+		B: a.B * y,
 		C: a.C,
-		D: a.D,
+		D: a.D * x,
 		E: a.E * y,
 		F: a.F,
 	}
 }
 
+// Apply the transform to all four corners of bounds and return the bounds
+// that contain them, which are larger than the transformed bounds if the
+// transform includes rotation
+func (a *Affine) TransformBounds(bounds *Bounds) *Bounds {
+	xs := [4]float64{}
+	ys := [4]float64{}
+	xs[0], ys[0] = a.Multiply(bounds.Xmin, bounds.Ymin)
+	xs[1], ys[1] = a.Multiply(bounds.Xmin, bounds.Ymax)
+	xs[2], ys[2] = a.Multiply(bounds.Xmax, bounds.Ymin)
+	xs[3], ys[3] = a.Multiply(bounds.Xmax, bounds.Ymax)
+
+	out := &Bounds{Xmin: math.Inf(1), Ymin: math.Inf(1), Xmax: math.Inf(-1), Ymax: math.Inf(-1)}
+	for i := 0; i < 4; i++ {
+		out.Xmin = math.Min(out.Xmin, xs[i])
+		out.Ymin = math.Min(out.Ymin, ys[i])
+		out.Xmax = math.Max(out.Xmax, xs[i])
+		out.Ymax = math.Max(out.Ymax, ys[i])
+	}
+	return out
+}
+
 func (a *Affine) String() string {
 	return fmt.Sprintf("Affine(%v, %v, %v,\n       %v, %v, %v)", a.A, a.B, a.C, a.D, a.E, a.F)
 }
 
-// Return the x, y resolution of the Affine transform
+// Return the x, y resolution of the Affine transform, which is the size of a
+// pixel along each axis of the raster if the transform includes rotation
 func (a *Affine) Resolution() (float64, float64) {
-	return math.Abs(a.A), math.Abs(a.E)
+	return math.Hypot(a.A, a.D), math.Hypot(a.B, a.E)
 }
@@ -91,3 +91,56 @@ func TestScale(t *testing.T) {
 		t.Errorf("%v did not match expected: %v", a, expected)
 	}
 }
+
+func TestScaleRotated(t *testing.T) {
+	a := (&Affine{A: 20, B: 10, C: 1000, D: 10, E: -20, F: 2000}).Scale(2, 3)
+	expected := Affine{A: 40, B: 30, C: 1000, D: 20, E: -60, F: 2000}
+	if *a != expected {
+		t.Errorf("%v did not match expected: %v", a, expected)
+	}
+}
+
+func TestResolution(t *testing.T) {
+	tests := []struct {
+		transform Affine
+		x         float64
+		y         float64
+	}{
+		{transform: Affine{A: 30, B: 0, C: 1000, D: 0, E: -30, F: 2000}, x: 30, y: 30},
+		// south-up
+		{transform: Affine{A: 30, B: 0, C: 1000, D: 0, E: 20, F: 2000}, x: 30, y: 20},
+		// rotated 90 degrees
+		{transform: Affine{A: 0, B: 30, C: 1000, D: 30, E: 0, F: 2000}, x: 30, y: 30},
+		{transform: Affine{A: 3, B: 4, C: 1000, D: -4, E: 3, F: 2000}, x: 5, y: 5},
+	}
+
+	for _, tc := range tests {
+		x, y := tc.transform.Resolution()
+		if !closeEnough(x, tc.x, 1e-9) || !closeEnough(y, tc.y, 1e-9) {
+			t.Errorf("resolution %v, %v of %v did not match expected: %v, %v", x, y, &tc.transform, tc.x, tc.y)
+		}
+	}
+}
+
+func TestTransformBounds(t *testing.T) {
+	pixels := &Bounds{Xmin: 0, Ymin: 0, Xmax: 10, Ymax: 20}
+
+	tests := []struct {
+		transform Affine
+		expected  Bounds
+	}{
+		// north-up
+		{transform: Affine{A: 30, B: 0, C: 1000, D: 0, E: -30, F: 2000}, expected: Bounds{Xmin: 1000, Ymin: 1400, Xmax: 1300, Ymax: 2000}},
+		// south-up, anchored from bottom left
+		{transform: Affine{A: 30, B: 0, C: 1000, D: 0, E: 30, F: 2000}, expected: Bounds{Xmin: 1000, Ymin: 2000, Xmax: 1300, Ymax: 2600}},
+		// rotated 45 degrees
+		{transform: Affine{A: 1, B: 1, C: 0, D: 1, E: -1, F: 0}, expected: Bounds{Xmin: 0, Ymin: -20, Xmax: 30, Ymax: 10}},
+	}
+
+	for _, tc := range tests {
+		bounds := tc.transform.TransformBounds(pixels)
+		if *bounds != tc.expected {
+			t.Errorf("%v did not match expected: %v", bounds, tc.expected)
+		}
+	}
+}
@@ -1,8 +1,25 @@
 package affine
 
+import "math"
+
 type Bounds struct {
 	Xmin float64
 	Ymin float64
 	Xmax float64
 	Ymax float64
 }
+
+// Intersection returns the bounds that are within both b and other, or nil if
+// they do not intersect
+func (b *Bounds) Intersection(other *Bounds) *Bounds {
+	out := &Bounds{
+		Xmin: math.Max(b.Xmin, other.Xmin),
+		Ymin: math.Max(b.Ymin, other.Ymin),
+		Xmax: math.Min(b.Xmax, other.Xmax),
+		Ymax: math.Min(b.Ymax, other.Ymax),
+	}
+	if out.Xmin > out.Xmax || out.Ymin > out.Ymax {
+		return nil
+	}
+	return out
+}
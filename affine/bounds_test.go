@@ -0,0 +1,23 @@
+package affine
+
+import "testing"
+
+func TestBoundsIntersection(t *testing.T) {
+	bounds := &Bounds{Xmin: 0, Ymin: 0, Xmax: 10, Ymax: 10}
+
+	tests := []struct {
+		other    Bounds
+		expected *Bounds
+	}{
+		{other: Bounds{Xmin: 5, Ymin: -5, Xmax: 15, Ymax: 5}, expected: &Bounds{Xmin: 5, Ymin: 0, Xmax: 10, Ymax: 5}},
+		{other: Bounds{Xmin: 2, Ymin: 2, Xmax: 4, Ymax: 4}, expected: &Bounds{Xmin: 2, Ymin: 2, Xmax: 4, Ymax: 4}},
+		{other: Bounds{Xmin: 20, Ymin: 0, Xmax: 30, Ymax: 10}, expected: nil},
+	}
+
+	for _, tc := range tests {
+		out := bounds.Intersection(&tc.other)
+		if (out == nil) != (tc.expected == nil) || (out != nil && *out != *tc.expected) {
+			t.Errorf("intersection with %v: %v did not match expected: %v", tc.other, out, tc.expected)
+		}
+	}
+}
@@ -1,9 +1,152 @@
 package array
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+)
+
+// Create a new buffer of size for dtype
+func NewBuffer(dtype string, size int) interface{} {
+	switch dtype {
+	case "int8":
+		return make([]int8, size)
+	case "uint8":
+		return make([]uint8, size)
+	case "int16":
+		return make([]int16, size)
+	case "uint16":
+		return make([]uint16, size)
+	case "int32":
+		return make([]int32, size)
+	case "uint32":
+		return make([]uint32, size)
+	case "float32":
+		return make([]float32, size)
+	case "float64":
+		return make([]float64, size)
+	default:
+		panic(fmt.Sprintf("other data types not yet supported for NewBuffer(): %v", dtype))
+	}
+}
+
+// Return the zero value for dtype
+func ZeroValue(dtype string) interface{} {
+	switch dtype {
+	case "int8":
+		return int8(0)
+	case "uint8":
+		return uint8(0)
+	case "int16":
+		return int16(0)
+	case "uint16":
+		return uint16(0)
+	case "int32":
+		return int32(0)
+	case "uint32":
+		return uint32(0)
+	case "float32":
+		return float32(0)
+	case "float64":
+		return float64(0)
+	default:
+		panic("other data types not yet supported for ZeroValue()")
+	}
+}
+
+// Convert value to dtype; returns an error if value cannot be represented
+// exactly by dtype, e.g., if it is fractional or out of range for an integer
+// dtype
+func FromFloat(value float64, dtype string) (interface{}, error) {
+	var typedValue interface{}
+	switch dtype {
+	case "int8":
+		typedValue = int8(value)
+	case "uint8":
+		typedValue = uint8(value)
+	case "int16":
+		typedValue = int16(value)
+	case "uint16":
+		typedValue = uint16(value)
+	case "int32":
+		typedValue = int32(value)
+	case "uint32":
+		typedValue = uint32(value)
+	case "float32":
+		if math.IsNaN(value) || float64(float32(value)) == value {
+			return float32(value), nil
+		}
+		return nil, fmt.Errorf("value %v cannot be represented as %v", value, dtype)
+	case "float64":
+		return value, nil
+	default:
+		panic("other data types not yet supported for FromFloat()")
+	}
+
+	if math.IsNaN(value) || ToFloat(typedValue) != value {
+		return nil, fmt.Errorf("value %v cannot be represented as %v", value, dtype)
+	}
+	return typedValue, nil
+}
+
+// Return the number of values in buffer
+func Len(buffer interface{}) int {
+	switch typedBuffer := buffer.(type) {
+	case []int8:
+		return len(typedBuffer)
+	case []uint8:
+		return len(typedBuffer)
+	case []int16:
+		return len(typedBuffer)
+	case []uint16:
+		return len(typedBuffer)
+	case []int32:
+		return len(typedBuffer)
+	case []uint32:
+		return len(typedBuffer)
+	case []float32:
+		return len(typedBuffer)
+	case []float64:
+		return len(typedBuffer)
+	default:
+		panic("other data types not yet supported for Len()")
+	}
+}
+
+// Return the slice of buffer from start to end, without copying; e.g., to
+// get a single band from a buffer that contains multiple bands
+func Slice(buffer interface{}, start int, end int) interface{} {
+	switch typedBuffer := buffer.(type) {
+	case []int8:
+		return typedBuffer[start:end]
+	case []uint8:
+		return typedBuffer[start:end]
+	case []int16:
+		return typedBuffer[start:end]
+	case []uint16:
+		return typedBuffer[start:end]
+	case []int32:
+		return typedBuffer[start:end]
+	case []uint32:
+		return typedBuffer[start:end]
+	case []float32:
+		return typedBuffer[start:end]
+	case []float64:
+		return typedBuffer[start:end]
+	default:
+		panic("other data types not yet supported for Slice()")
+	}
+}
 
 func AllEquals(buffer interface{}, value interface{}) bool {
 	switch typedBuffer := buffer.(type) {
+	case []int8:
+		typedValue := value.(int8)
+		for i := 0; i < len(typedBuffer); i++ {
+			if typedBuffer[i] != typedValue {
+				return false
+			}
+		}
+		return true
 	case []uint8:
 		typedValue := value.(uint8)
 		for i := 0; i < len(typedBuffer); i++ {
@@ -12,6 +155,14 @@ func AllEquals(buffer interface{}, value interface{}) bool {
 			}
 		}
 		return true
+	case []int16:
+		typedValue := value.(int16)
+		for i := 0; i < len(typedBuffer); i++ {
+			if typedBuffer[i] != typedValue {
+				return false
+			}
+		}
+		return true
 	case []uint16:
 		typedValue := value.(uint16)
 		for i := 0; i < len(typedBuffer); i++ {
@@ -20,6 +171,14 @@ func AllEquals(buffer interface{}, value interface{}) bool {
 			}
 		}
 		return true
+	case []int32:
+		typedValue := value.(int32)
+		for i := 0; i < len(typedBuffer); i++ {
+			if typedBuffer[i] != typedValue {
+				return false
+			}
+		}
+		return true
 	case []uint32:
 		typedValue := value.(uint32)
 		for i := 0; i < len(typedBuffer); i++ {
@@ -28,6 +187,25 @@ func AllEquals(buffer interface{}, value interface{}) bool {
 			}
 		}
 		return true
+	case []float32:
+		typedValue := value.(float32)
+		// NaN is never equal to itself, so must be checked separately
+		isNaN := math.IsNaN(float64(typedValue))
+		for i := 0; i < len(typedBuffer); i++ {
+			if typedBuffer[i] != typedValue && !(isNaN && math.IsNaN(float64(typedBuffer[i]))) {
+				return false
+			}
+		}
+		return true
+	case []float64:
+		typedValue := value.(float64)
+		isNaN := math.IsNaN(typedValue)
+		for i := 0; i < len(typedBuffer); i++ {
+			if typedBuffer[i] != typedValue && !(isNaN && math.IsNaN(typedBuffer[i])) {
+				return false
+			}
+		}
+		return true
 	default:
 		panic("other data types not yet supported for AllEquals()")
 	}
@@ -37,6 +215,17 @@ func AllEquals(buffer interface{}, value interface{}) bool {
 // will fail if arrays do not have same type
 func Equals(left interface{}, right interface{}) bool {
 	switch leftBuffer := left.(type) {
+	case []int8:
+		rightBuffer := right.([]int8)
+		if len(leftBuffer) != len(rightBuffer) {
+			return false
+		}
+		for i := 0; i < len(leftBuffer); i++ {
+			if leftBuffer[i] != rightBuffer[i] {
+				return false
+			}
+		}
+		return true
 	case []uint8:
 		rightBuffer := right.([]uint8)
 		if len(leftBuffer) != len(rightBuffer) {
@@ -48,6 +237,17 @@ func Equals(left interface{}, right interface{}) bool {
 			}
 		}
 		return true
+	case []int16:
+		rightBuffer := right.([]int16)
+		if len(leftBuffer) != len(rightBuffer) {
+			return false
+		}
+		for i := 0; i < len(leftBuffer); i++ {
+			if leftBuffer[i] != rightBuffer[i] {
+				return false
+			}
+		}
+		return true
 	case []uint16:
 		rightBuffer := right.([]uint16)
 		if len(leftBuffer) != len(rightBuffer) {
@@ -59,6 +259,17 @@ func Equals(left interface{}, right interface{}) bool {
 			}
 		}
 		return true
+	case []int32:
+		rightBuffer := right.([]int32)
+		if len(leftBuffer) != len(rightBuffer) {
+			return false
+		}
+		for i := 0; i < len(leftBuffer); i++ {
+			if leftBuffer[i] != rightBuffer[i] {
+				return false
+			}
+		}
+		return true
 	case []uint32:
 		rightBuffer := right.([]uint32)
 		if len(leftBuffer) != len(rightBuffer) {
@@ -70,6 +281,28 @@ func Equals(left interface{}, right interface{}) bool {
 			}
 		}
 		return true
+	case []float32:
+		rightBuffer := right.([]float32)
+		if len(leftBuffer) != len(rightBuffer) {
+			return false
+		}
+		for i := 0; i < len(leftBuffer); i++ {
+			if leftBuffer[i] != rightBuffer[i] {
+				return false
+			}
+		}
+		return true
+	case []float64:
+		rightBuffer := right.([]float64)
+		if len(leftBuffer) != len(rightBuffer) {
+			return false
+		}
+		for i := 0; i < len(leftBuffer); i++ {
+			if leftBuffer[i] != rightBuffer[i] {
+				return false
+			}
+		}
+		return true
 	default:
 		panic("other data types not yet supported for Equals()")
 	}
@@ -77,23 +310,48 @@ func Equals(left interface{}, right interface{}) bool {
 
 func Fill(buffer interface{}, value interface{}) {
 	switch typedBuffer := buffer.(type) {
+	case []int8:
+		typedValue := value.(int8)
+		for i := 0; i < len(typedBuffer); i++ {
+			typedBuffer[i] = typedValue
+		}
 	case []uint8:
 		typedValue := value.(uint8)
 		for i := 0; i < len(typedBuffer); i++ {
 			typedBuffer[i] = typedValue
 		}
+	case []int16:
+		typedValue := value.(int16)
+		for i := 0; i < len(typedBuffer); i++ {
+			typedBuffer[i] = typedValue
+		}
 	case []uint16:
 		typedValue := value.(uint16)
 		for i := 0; i < len(typedBuffer); i++ {
 			typedBuffer[i] = typedValue
 		}
+	case []int32:
+		typedValue := value.(int32)
+		for i := 0; i < len(typedBuffer); i++ {
+			typedBuffer[i] = typedValue
+		}
 	case []uint32:
 		typedValue := value.(uint32)
 		for i := 0; i < len(typedBuffer); i++ {
 			typedBuffer[i] = typedValue
 		}
+	case []float32:
+		typedValue := value.(float32)
+		for i := 0; i < len(typedBuffer); i++ {
+			typedBuffer[i] = typedValue
+		}
+	case []float64:
+		typedValue := value.(float64)
+		for i := 0; i < len(typedBuffer); i++ {
+			typedBuffer[i] = typedValue
+		}
 	default:
-		panic("other data types not yet supported for AllEquals()")
+		panic("other data types not yet supported for Fill()")
 	}
 }
 
@@ -112,6 +370,15 @@ func Paste(target interface{}, targetHeight int, targetWidth int, source interfa
 	var srcIndex int
 
 	switch targetBuffer := target.(type) {
+	case []int8:
+		sourceBuffer := source.([]int8)
+		for row := rowOffset; row < rowOffset+sourceHeight; row++ {
+			for col := colOffset; col < colOffset+sourceWidth; col++ {
+				i = row*targetWidth + col
+				srcIndex = (row-rowOffset)*sourceWidth + (col - colOffset)
+				targetBuffer[i] = sourceBuffer[srcIndex]
+			}
+		}
 	case []uint8:
 		sourceBuffer := source.([]uint8)
 		for row := rowOffset; row < rowOffset+sourceHeight; row++ {
@@ -121,6 +388,15 @@ func Paste(target interface{}, targetHeight int, targetWidth int, source interfa
 				targetBuffer[i] = sourceBuffer[srcIndex]
 			}
 		}
+	case []int16:
+		sourceBuffer := source.([]int16)
+		for row := rowOffset; row < rowOffset+sourceHeight; row++ {
+			for col := colOffset; col < colOffset+sourceWidth; col++ {
+				i = row*targetWidth + col
+				srcIndex = (row-rowOffset)*sourceWidth + (col - colOffset)
+				targetBuffer[i] = sourceBuffer[srcIndex]
+			}
+		}
 	case []uint16:
 		sourceBuffer := source.([]uint16)
 		for row := rowOffset; row < rowOffset+sourceHeight; row++ {
@@ -130,6 +406,15 @@ func Paste(target interface{}, targetHeight int, targetWidth int, source interfa
 				targetBuffer[i] = sourceBuffer[srcIndex]
 			}
 		}
+	case []int32:
+		sourceBuffer := source.([]int32)
+		for row := rowOffset; row < rowOffset+sourceHeight; row++ {
+			for col := colOffset; col < colOffset+sourceWidth; col++ {
+				i = row*targetWidth + col
+				srcIndex = (row-rowOffset)*sourceWidth + (col - colOffset)
+				targetBuffer[i] = sourceBuffer[srcIndex]
+			}
+		}
 	case []uint32:
 		sourceBuffer := source.([]uint32)
 		for row := rowOffset; row < rowOffset+sourceHeight; row++ {
@@ -139,6 +424,24 @@ func Paste(target interface{}, targetHeight int, targetWidth int, source interfa
 				targetBuffer[i] = sourceBuffer[srcIndex]
 			}
 		}
+	case []float32:
+		sourceBuffer := source.([]float32)
+		for row := rowOffset; row < rowOffset+sourceHeight; row++ {
+			for col := colOffset; col < colOffset+sourceWidth; col++ {
+				i = row*targetWidth + col
+				srcIndex = (row-rowOffset)*sourceWidth + (col - colOffset)
+				targetBuffer[i] = sourceBuffer[srcIndex]
+			}
+		}
+	case []float64:
+		sourceBuffer := source.([]float64)
+		for row := rowOffset; row < rowOffset+sourceHeight; row++ {
+			for col := colOffset; col < colOffset+sourceWidth; col++ {
+				i = row*targetWidth + col
+				srcIndex = (row-rowOffset)*sourceWidth + (col - colOffset)
+				targetBuffer[i] = sourceBuffer[srcIndex]
+			}
+		}
 	default:
 		panic("other dtypes not yet supported for Paste()")
 	}
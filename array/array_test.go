@@ -1,9 +1,35 @@
 package array
 
 import (
+	"math"
 	"testing"
 )
 
+func TestNewBuffer(t *testing.T) {
+	buffer := NewBuffer("uint16", 4)
+	typedBuffer, ok := buffer.([]uint16)
+	if !ok {
+		t.Fatalf("NewBuffer() returned unexpected type: %T", buffer)
+	}
+	if len(typedBuffer) != 4 {
+		t.Errorf("NewBuffer() returned unexpected size: %v", len(typedBuffer))
+	}
+}
+
+func TestSlice(t *testing.T) {
+	buffer := []uint16{1, 1, 2, 2, 3, 3}
+	band := Slice(buffer, 2, 4).([]uint16)
+	if !Equals(band, []uint16{2, 2}) {
+		t.Errorf("Slice() returned unexpected values: %v", band)
+	}
+
+	// slice shares memory with buffer
+	Fill(band, uint16(0))
+	if !Equals(buffer, []uint16{1, 1, 0, 0, 3, 3}) {
+		t.Errorf("Slice() did not return a view of buffer: %v", buffer)
+	}
+}
+
 func TestAllEquals(t *testing.T) {
 	size := 4
 	var fill uint8 = 0
@@ -97,3 +123,53 @@ func TestPaste(t *testing.T) {
 		t.Errorf("data:\n%v\ndoes not match expected:\n%v", target, expected)
 	}
 }
+
+func TestSigned(t *testing.T) {
+	for _, dtype := range []string{"int8", "int16", "int32"} {
+		target := NewBuffer(dtype, 4)
+		fill := ZeroValue(dtype)
+		Fill(target, fill)
+		if !AllEquals(target, fill) {
+			t.Errorf("%v: Fill() did not set all values as expected", dtype)
+		}
+
+		source := NewBuffer(dtype, 1)
+		_, set := Accessors(source)
+		set(0, -5)
+		Paste(target, 2, 2, source, 1, 1, 1, 1)
+
+		get, _ := Accessors(target)
+		if value := get(3); value != -5 {
+			t.Errorf("%v: Paste() set value %v instead of -5", dtype, value)
+		}
+		if Equals(target, NewBuffer(dtype, 4)) {
+			t.Errorf("%v: Equals() returned true when should have returned false", dtype)
+		}
+	}
+}
+
+func TestFromFloat(t *testing.T) {
+	value, err := FromFloat(-5, "int16")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != int16(-5) {
+		t.Errorf("FromFloat() returned unexpected value: %v (%T)", value, value)
+	}
+
+	invalid := []struct {
+		value float64
+		dtype string
+	}{
+		{0.5, "uint8"},
+		{256, "uint8"},
+		{-1, "uint16"},
+		{math.NaN(), "int32"},
+		{0.1, "float32"},
+	}
+	for _, tc := range invalid {
+		if _, err := FromFloat(tc.value, tc.dtype); err == nil {
+			t.Errorf("FromFloat(%v, %v) did not raise error", tc.value, tc.dtype)
+		}
+	}
+}
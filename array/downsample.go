@@ -0,0 +1,539 @@
+package array
+
+import (
+	"fmt"
+	"math"
+)
+
+// Reducer determines how a 2x2 block of pixels is combined into a single
+// pixel when downsampling
+type Reducer int
+
+const (
+	// use upper left valid pixel of block
+	Nearest Reducer = iota
+	// use most common value of valid pixels of block; ties are resolved in
+	// favor of the first value found.  Appropriate for categorical data.
+	Mode
+	// use mean of valid pixels of block
+	Mean
+)
+
+func ParseReducer(reducer string) (Reducer, error) {
+	switch reducer {
+	case "nearest":
+		return Nearest, nil
+	case "mode":
+		return Mode, nil
+	case "mean":
+		return Mean, nil
+	default:
+		return Nearest, fmt.Errorf("reducer must be one of nearest, mode, mean: %v", reducer)
+	}
+}
+
+func (r Reducer) String() string {
+	switch r {
+	case Mode:
+		return "mode"
+	case Mean:
+		return "mean"
+	default:
+		return "nearest"
+	}
+}
+
+// Get accessors that read and write values of buffer as float64, which can
+// exactly represent all supported types.  Values are rounded when set for
+// integer types.
+func Accessors(buffer interface{}) (get func(i int) float64, set func(i int, value float64)) {
+	switch typedBuffer := buffer.(type) {
+	case []int8:
+		get = func(i int) float64 { return float64(typedBuffer[i]) }
+		set = func(i int, value float64) { typedBuffer[i] = int8(math.Round(value)) }
+	case []uint8:
+		get = func(i int) float64 { return float64(typedBuffer[i]) }
+		set = func(i int, value float64) { typedBuffer[i] = uint8(math.Round(value)) }
+	case []int16:
+		get = func(i int) float64 { return float64(typedBuffer[i]) }
+		set = func(i int, value float64) { typedBuffer[i] = int16(math.Round(value)) }
+	case []uint16:
+		get = func(i int) float64 { return float64(typedBuffer[i]) }
+		set = func(i int, value float64) { typedBuffer[i] = uint16(math.Round(value)) }
+	case []int32:
+		get = func(i int) float64 { return float64(typedBuffer[i]) }
+		set = func(i int, value float64) { typedBuffer[i] = int32(math.Round(value)) }
+	case []uint32:
+		get = func(i int) float64 { return float64(typedBuffer[i]) }
+		set = func(i int, value float64) { typedBuffer[i] = uint32(math.Round(value)) }
+	case []float32:
+		get = func(i int) float64 { return float64(typedBuffer[i]) }
+		set = func(i int, value float64) { typedBuffer[i] = float32(value) }
+	case []float64:
+		get = func(i int) float64 { return typedBuffer[i] }
+		set = func(i int, value float64) { typedBuffer[i] = value }
+	default:
+		panic("other data types not yet supported for Accessors()")
+	}
+	return
+}
+
+// Convert value of any supported type to float64
+func ToFloat(value interface{}) float64 {
+	switch typedValue := value.(type) {
+	case int8:
+		return float64(typedValue)
+	case uint8:
+		return float64(typedValue)
+	case int16:
+		return float64(typedValue)
+	case uint16:
+		return float64(typedValue)
+	case int32:
+		return float64(typedValue)
+	case uint32:
+		return float64(typedValue)
+	case float32:
+		return float64(typedValue)
+	case float64:
+		return typedValue
+	default:
+		panic("other data types not yet supported for ToFloat()")
+	}
+}
+
+// Downsample source 2D array of size x size by a factor of 2 into target 2D
+// array of size x size, starting at rowOffset, colOffset.  Target, source,
+// and fill must be of same dtype, and size must be even.  Pixels of source
+// where mask is 0 and NaN pixels are not valid and are ignored; mask may be
+// nil if all pixels are valid.  Blocks without valid pixels are set to fill.
+func Downsample(target interface{}, source interface{}, mask []uint8, size int, rowOffset int, colOffset int, fill interface{}, reducer Reducer) error {
+	half := size / 2
+	if rowOffset < 0 || colOffset < 0 || rowOffset+half > size || colOffset+half > size {
+		return fmt.Errorf("offsets must be within target array")
+	}
+
+	switch targetBuffer := target.(type) {
+	case []int8:
+		downsampleInt8(targetBuffer, source.([]int8), mask, size, rowOffset, colOffset, fill.(int8), reducer)
+	case []uint8:
+		downsampleUint8(targetBuffer, source.([]uint8), mask, size, rowOffset, colOffset, fill.(uint8), reducer)
+	case []int16:
+		downsampleInt16(targetBuffer, source.([]int16), mask, size, rowOffset, colOffset, fill.(int16), reducer)
+	case []uint16:
+		downsampleUint16(targetBuffer, source.([]uint16), mask, size, rowOffset, colOffset, fill.(uint16), reducer)
+	case []int32:
+		downsampleInt32(targetBuffer, source.([]int32), mask, size, rowOffset, colOffset, fill.(int32), reducer)
+	case []uint32:
+		downsampleUint32(targetBuffer, source.([]uint32), mask, size, rowOffset, colOffset, fill.(uint32), reducer)
+	case []float32:
+		downsampleFloat32(targetBuffer, source.([]float32), mask, size, rowOffset, colOffset, fill.(float32), reducer)
+	case []float64:
+		downsampleFloat64(targetBuffer, source.([]float64), mask, size, rowOffset, colOffset, fill.(float64), reducer)
+	default:
+		panic("other data types not yet supported for Downsample()")
+	}
+
+	return nil
+}
+
+// Downsample source mask of size x size by a factor of 2 into target mask of
+// size x size, starting at rowOffset, colOffset.  Each pixel is the maximum of
+// its block, so that it is valid if any pixel of the block is valid.
+func DownsampleMask(target []uint8, source []uint8, size int, rowOffset int, colOffset int) error {
+	half := size / 2
+	if rowOffset < 0 || colOffset < 0 || rowOffset+half > size || colOffset+half > size {
+		return fmt.Errorf("offsets must be within target array")
+	}
+
+	for row := 0; row < half; row++ {
+		for col := 0; col < half; col++ {
+			i := 2*row*size + 2*col
+			value := source[i]
+			for _, offset := range [3]int{i + 1, i + size, i + size + 1} {
+				if source[offset] > value {
+					value = source[offset]
+				}
+			}
+			target[(row+rowOffset)*size+col+colOffset] = value
+		}
+	}
+
+	return nil
+}
+
+// Downsample int8 source into target; see Downsample()
+func downsampleInt8(target []int8, source []int8, mask []uint8, size int, rowOffset int, colOffset int, fill int8, reducer Reducer) {
+	half := size / 2
+	var block [4]int8 // valid pixels of block, in order
+	for row := 0; row < half; row++ {
+		for col := 0; col < half; col++ {
+			i := 2*row*size + 2*col
+			count := 0
+			for _, offset := range [4]int{i, i + 1, i + size, i + size + 1} {
+				if value := source[offset]; mask == nil || mask[offset] != 0 {
+					block[count] = value
+					count++
+				}
+			}
+
+			value := fill
+			if count > 0 {
+				switch reducer {
+				case Mode:
+					maxCount := 0
+					for j := 0; j < count; j++ {
+						n := 0
+						for k := j; k < count; k++ {
+							if block[k] == block[j] {
+								n++
+							}
+						}
+						if n > maxCount {
+							value = block[j]
+							maxCount = n
+						}
+					}
+				case Mean:
+					var sum int64
+					for _, value := range block[:count] {
+						sum += int64(value)
+					}
+					value = int8(math.Round(float64(sum) / float64(count)))
+				default:
+					value = block[0]
+				}
+			}
+			target[(row+rowOffset)*size+col+colOffset] = value
+		}
+	}
+}
+
+// Downsample uint8 source into target; see Downsample()
+func downsampleUint8(target []uint8, source []uint8, mask []uint8, size int, rowOffset int, colOffset int, fill uint8, reducer Reducer) {
+	half := size / 2
+	var block [4]uint8 // valid pixels of block, in order
+	for row := 0; row < half; row++ {
+		for col := 0; col < half; col++ {
+			i := 2*row*size + 2*col
+			count := 0
+			for _, offset := range [4]int{i, i + 1, i + size, i + size + 1} {
+				if value := source[offset]; mask == nil || mask[offset] != 0 {
+					block[count] = value
+					count++
+				}
+			}
+
+			value := fill
+			if count > 0 {
+				switch reducer {
+				case Mode:
+					maxCount := 0
+					for j := 0; j < count; j++ {
+						n := 0
+						for k := j; k < count; k++ {
+							if block[k] == block[j] {
+								n++
+							}
+						}
+						if n > maxCount {
+							value = block[j]
+							maxCount = n
+						}
+					}
+				case Mean:
+					var sum int64
+					for _, value := range block[:count] {
+						sum += int64(value)
+					}
+					value = uint8(math.Round(float64(sum) / float64(count)))
+				default:
+					value = block[0]
+				}
+			}
+			target[(row+rowOffset)*size+col+colOffset] = value
+		}
+	}
+}
+
+// Downsample int16 source into target; see Downsample()
+func downsampleInt16(target []int16, source []int16, mask []uint8, size int, rowOffset int, colOffset int, fill int16, reducer Reducer) {
+	half := size / 2
+	var block [4]int16 // valid pixels of block, in order
+	for row := 0; row < half; row++ {
+		for col := 0; col < half; col++ {
+			i := 2*row*size + 2*col
+			count := 0
+			for _, offset := range [4]int{i, i + 1, i + size, i + size + 1} {
+				if value := source[offset]; mask == nil || mask[offset] != 0 {
+					block[count] = value
+					count++
+				}
+			}
+
+			value := fill
+			if count > 0 {
+				switch reducer {
+				case Mode:
+					maxCount := 0
+					for j := 0; j < count; j++ {
+						n := 0
+						for k := j; k < count; k++ {
+							if block[k] == block[j] {
+								n++
+							}
+						}
+						if n > maxCount {
+							value = block[j]
+							maxCount = n
+						}
+					}
+				case Mean:
+					var sum int64
+					for _, value := range block[:count] {
+						sum += int64(value)
+					}
+					value = int16(math.Round(float64(sum) / float64(count)))
+				default:
+					value = block[0]
+				}
+			}
+			target[(row+rowOffset)*size+col+colOffset] = value
+		}
+	}
+}
+
+// Downsample uint16 source into target; see Downsample()
+func downsampleUint16(target []uint16, source []uint16, mask []uint8, size int, rowOffset int, colOffset int, fill uint16, reducer Reducer) {
+	half := size / 2
+	var block [4]uint16 // valid pixels of block, in order
+	for row := 0; row < half; row++ {
+		for col := 0; col < half; col++ {
+			i := 2*row*size + 2*col
+			count := 0
+			for _, offset := range [4]int{i, i + 1, i + size, i + size + 1} {
+				if value := source[offset]; mask == nil || mask[offset] != 0 {
+					block[count] = value
+					count++
+				}
+			}
+
+			value := fill
+			if count > 0 {
+				switch reducer {
+				case Mode:
+					maxCount := 0
+					for j := 0; j < count; j++ {
+						n := 0
+						for k := j; k < count; k++ {
+							if block[k] == block[j] {
+								n++
+							}
+						}
+						if n > maxCount {
+							value = block[j]
+							maxCount = n
+						}
+					}
+				case Mean:
+					var sum int64
+					for _, value := range block[:count] {
+						sum += int64(value)
+					}
+					value = uint16(math.Round(float64(sum) / float64(count)))
+				default:
+					value = block[0]
+				}
+			}
+			target[(row+rowOffset)*size+col+colOffset] = value
+		}
+	}
+}
+
+// Downsample int32 source into target; see Downsample()
+func downsampleInt32(target []int32, source []int32, mask []uint8, size int, rowOffset int, colOffset int, fill int32, reducer Reducer) {
+	half := size / 2
+	var block [4]int32 // valid pixels of block, in order
+	for row := 0; row < half; row++ {
+		for col := 0; col < half; col++ {
+			i := 2*row*size + 2*col
+			count := 0
+			for _, offset := range [4]int{i, i + 1, i + size, i + size + 1} {
+				if value := source[offset]; mask == nil || mask[offset] != 0 {
+					block[count] = value
+					count++
+				}
+			}
+
+			value := fill
+			if count > 0 {
+				switch reducer {
+				case Mode:
+					maxCount := 0
+					for j := 0; j < count; j++ {
+						n := 0
+						for k := j; k < count; k++ {
+							if block[k] == block[j] {
+								n++
+							}
+						}
+						if n > maxCount {
+							value = block[j]
+							maxCount = n
+						}
+					}
+				case Mean:
+					var sum int64
+					for _, value := range block[:count] {
+						sum += int64(value)
+					}
+					value = int32(math.Round(float64(sum) / float64(count)))
+				default:
+					value = block[0]
+				}
+			}
+			target[(row+rowOffset)*size+col+colOffset] = value
+		}
+	}
+}
+
+// Downsample uint32 source into target; see Downsample()
+func downsampleUint32(target []uint32, source []uint32, mask []uint8, size int, rowOffset int, colOffset int, fill uint32, reducer Reducer) {
+	half := size / 2
+	var block [4]uint32 // valid pixels of block, in order
+	for row := 0; row < half; row++ {
+		for col := 0; col < half; col++ {
+			i := 2*row*size + 2*col
+			count := 0
+			for _, offset := range [4]int{i, i + 1, i + size, i + size + 1} {
+				if value := source[offset]; mask == nil || mask[offset] != 0 {
+					block[count] = value
+					count++
+				}
+			}
+
+			value := fill
+			if count > 0 {
+				switch reducer {
+				case Mode:
+					maxCount := 0
+					for j := 0; j < count; j++ {
+						n := 0
+						for k := j; k < count; k++ {
+							if block[k] == block[j] {
+								n++
+							}
+						}
+						if n > maxCount {
+							value = block[j]
+							maxCount = n
+						}
+					}
+				case Mean:
+					var sum int64
+					for _, value := range block[:count] {
+						sum += int64(value)
+					}
+					value = uint32(math.Round(float64(sum) / float64(count)))
+				default:
+					value = block[0]
+				}
+			}
+			target[(row+rowOffset)*size+col+colOffset] = value
+		}
+	}
+}
+
+// Downsample float32 source into target; see Downsample()
+func downsampleFloat32(target []float32, source []float32, mask []uint8, size int, rowOffset int, colOffset int, fill float32, reducer Reducer) {
+	half := size / 2
+	var block [4]float32 // valid pixels of block, in order
+	for row := 0; row < half; row++ {
+		for col := 0; col < half; col++ {
+			i := 2*row*size + 2*col
+			count := 0
+			for _, offset := range [4]int{i, i + 1, i + size, i + size + 1} {
+				if value := source[offset]; (mask == nil || mask[offset] != 0) && !math.IsNaN(float64(value)) {
+					block[count] = value
+					count++
+				}
+			}
+
+			value := fill
+			if count > 0 {
+				switch reducer {
+				case Mode:
+					maxCount := 0
+					for j := 0; j < count; j++ {
+						n := 0
+						for k := j; k < count; k++ {
+							if block[k] == block[j] {
+								n++
+							}
+						}
+						if n > maxCount {
+							value = block[j]
+							maxCount = n
+						}
+					}
+				case Mean:
+					sum := 0.0
+					for _, value := range block[:count] {
+						sum += float64(value)
+					}
+					value = float32(sum / float64(count))
+				default:
+					value = block[0]
+				}
+			}
+			target[(row+rowOffset)*size+col+colOffset] = value
+		}
+	}
+}
+
+// Downsample float64 source into target; see Downsample()
+func downsampleFloat64(target []float64, source []float64, mask []uint8, size int, rowOffset int, colOffset int, fill float64, reducer Reducer) {
+	half := size / 2
+	var block [4]float64 // valid pixels of block, in order
+	for row := 0; row < half; row++ {
+		for col := 0; col < half; col++ {
+			i := 2*row*size + 2*col
+			count := 0
+			for _, offset := range [4]int{i, i + 1, i + size, i + size + 1} {
+				if value := source[offset]; (mask == nil || mask[offset] != 0) && !math.IsNaN(float64(value)) {
+					block[count] = value
+					count++
+				}
+			}
+
+			value := fill
+			if count > 0 {
+				switch reducer {
+				case Mode:
+					maxCount := 0
+					for j := 0; j < count; j++ {
+						n := 0
+						for k := j; k < count; k++ {
+							if block[k] == block[j] {
+								n++
+							}
+						}
+						if n > maxCount {
+							value = block[j]
+							maxCount = n
+						}
+					}
+				case Mean:
+					sum := 0.0
+					for _, value := range block[:count] {
+						sum += float64(value)
+					}
+					value = float64(sum / float64(count))
+				default:
+					value = block[0]
+				}
+			}
+			target[(row+rowOffset)*size+col+colOffset] = value
+		}
+	}
+}
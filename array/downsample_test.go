@@ -0,0 +1,148 @@
+package array
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDownsample(t *testing.T) {
+	var fill uint8 = 255
+	size := 4
+	source := []uint8{
+		1, 2, 3, 3,
+		2, 2, 0, 0,
+		0, 0, 5, 6,
+		0, 4, 6, 6,
+	}
+	mask := []uint8{
+		255, 255, 255, 255,
+		255, 255, 255, 255,
+		0, 0, 0, 255,
+		0, 255, 255, 255,
+	}
+
+	tests := []struct {
+		reducer  Reducer
+		mask     []uint8
+		expected []uint8
+	}{
+		// zero is a valid value without a mask
+		{reducer: Nearest, expected: []uint8{1, 3, 0, 5}},
+		{reducer: Mode, expected: []uint8{2, 3, 0, 6}},
+		{reducer: Mean, expected: []uint8{2, 2, 1, 6}},
+		{reducer: Nearest, mask: mask, expected: []uint8{1, 3, 4, 6}},
+		{reducer: Mode, mask: mask, expected: []uint8{2, 3, 4, 6}},
+		{reducer: Mean, mask: mask, expected: []uint8{2, 2, 4, 6}},
+	}
+
+	for _, tc := range tests {
+		target := make([]uint8, size*size)
+		Fill(target, fill)
+
+		// downsample into lower right quadrant
+		err := Downsample(target, source, tc.mask, size, 2, 2, fill, tc.reducer)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := make([]uint8, size*size)
+		Fill(expected, fill)
+		expected[2*size+2] = tc.expected[0]
+		expected[2*size+3] = tc.expected[1]
+		expected[3*size+2] = tc.expected[2]
+		expected[3*size+3] = tc.expected[3]
+
+		if !Equals(target, expected) {
+			t.Errorf("%v (mask: %v): %v does not match expected: %v", tc.reducer, tc.mask != nil, target, expected)
+		}
+	}
+
+	// blocks without valid pixels are set to fill
+	target := make([]uint8, size*size)
+	if err := Downsample(target, source, make([]uint8, size*size), size, 0, 0, fill, Mean); err != nil {
+		t.Fatal(err)
+	}
+	if target[0] != fill || target[size+1] != fill {
+		t.Errorf("blocks without valid pixels are not fill: %v", target)
+	}
+}
+
+func TestDownsampleMask(t *testing.T) {
+	size := 4
+	source := []uint8{
+		255, 0, 0, 0,
+		0, 0, 0, 0,
+		128, 0, 255, 255,
+		0, 64, 255, 255,
+	}
+	target := make([]uint8, size*size)
+	if err := DownsampleMask(target, source, size, 0, 2); err != nil {
+		t.Fatal(err)
+	}
+	expected := []uint8{
+		0, 0, 255, 0,
+		0, 0, 128, 255,
+		0, 0, 0, 0,
+		0, 0, 0, 0,
+	}
+	if !Equals(target, expected) {
+		t.Errorf("%v does not match expected: %v", target, expected)
+	}
+}
+
+func TestDownsampleMean(t *testing.T) {
+	nan := math.NaN()
+	size := 2
+	tests := []struct {
+		source   []float64
+		mask     []uint8
+		expected float64
+	}{
+		{source: []float64{1, 2, 0, 0}, expected: 0.75},
+		{source: []float64{1, 2, 0, 0}, mask: []uint8{255, 255, 0, 0}, expected: 1.5},
+		{source: []float64{1, 2, 3, 4}, mask: []uint8{0, 0, 0, 0}, expected: 10},
+		{source: []float64{nan, 2, 4, nan}, expected: 3},
+	}
+
+	for _, tc := range tests {
+		target := make([]float64, size*size)
+		if err := Downsample(target, tc.source, tc.mask, size, 0, 0, float64(10), Mean); err != nil {
+			t.Fatal(err)
+		}
+		if target[0] != tc.expected {
+			t.Errorf("%v (mask: %v): %v not expected value: %v", tc.source, tc.mask, target[0], tc.expected)
+		}
+	}
+
+	// mean of integer types is rounded
+	source := []int16{-1, -2, 1, 0}
+	target := make([]int16, size*size)
+	if err := Downsample(target, source, nil, size, 0, 0, int16(0), Mean); err != nil {
+		t.Fatal(err)
+	}
+	if target[0] != -1 {
+		t.Errorf("%v not expected value: -1", target[0])
+	}
+}
+
+func BenchmarkDownsample(b *testing.B) {
+	size := 512
+	mask := make([]uint8, size*size)
+	Fill(mask, uint8(255))
+
+	for _, dtype := range []string{"uint8", "int16", "float32"} {
+		source := NewBuffer(dtype, size*size)
+		target := NewBuffer(dtype, size*size)
+		fill := ZeroValue(dtype)
+		for _, reducer := range []Reducer{Nearest, Mode, Mean} {
+			b.Run(dtype+"/"+reducer.String(), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					// downsample four child tiles into each quadrant
+					for quadrant := 0; quadrant < 4; quadrant++ {
+						Downsample(target, source, mask, size, (quadrant/2)*size/2, (quadrant%2)*size/2, fill, reducer)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package array
+
+import (
+	"fmt"
+	"math"
+)
+
+// Rescale linearly maps values of source between min and max into the range
+// of target, which must be uint8 or uint16.  Values outside min and max are
+// clamped.  Nodata and NaN values in source are set to 0 in target, and all
+// other values are mapped to 1 through the maximum value of the target dtype
+// so that they remain distinct from nodata.  Nodata is optional.
+func Rescale(target interface{}, source interface{}, min float64, max float64, nodata interface{}) error {
+	if max <= min {
+		return fmt.Errorf("rescale max must be greater than min")
+	}
+
+	var maxValue float64
+	switch target.(type) {
+	case []uint8:
+		maxValue = math.MaxUint8
+	case []uint16:
+		maxValue = math.MaxUint16
+	default:
+		return fmt.Errorf("rescale target must be uint8 or uint16")
+	}
+
+	get, _ := Accessors(source)
+	_, set := Accessors(target)
+	size := Len(source)
+
+	hasNodata := nodata != nil
+	var nodataValue float64
+	if hasNodata {
+		nodataValue = ToFloat(nodata)
+	}
+
+	scale := (maxValue - 1) / (max - min)
+	var value float64
+	for i := 0; i < size; i++ {
+		value = get(i)
+		if math.IsNaN(value) || (hasNodata && value == nodataValue) {
+			set(i, 0)
+			continue
+		}
+		set(i, 1+(math.Min(math.Max(value, min), max)-min)*scale)
+	}
+
+	return nil
+}
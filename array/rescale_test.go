@@ -0,0 +1,35 @@
+package array
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRescale(t *testing.T) {
+	nan := float32(math.NaN())
+	source := []float32{-1, 0, 0.5, 1, 2, nan, -9999}
+	target := make([]uint8, len(source))
+
+	if err := Rescale(target, source, 0, 1, float32(-9999)); err != nil {
+		t.Fatal(err)
+	}
+	expected := []uint8{1, 1, 128, 255, 255, 0, 0}
+	if !Equals(target, expected) {
+		t.Errorf("Rescale() returned %v, expected %v", target, expected)
+	}
+
+	target16 := make([]uint16, 2)
+	if err := Rescale(target16, []float64{10, 20}, 10, 20, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !Equals(target16, []uint16{1, math.MaxUint16}) {
+		t.Errorf("Rescale() returned unexpected values: %v", target16)
+	}
+
+	if err := Rescale(target, source, 1, 0, nil); err == nil {
+		t.Errorf("Rescale() did not return error for max < min")
+	}
+	if err := Rescale(make([]float32, 2), source, 0, 1, nil); err == nil {
+		t.Errorf("Rescale() did not return error for float target")
+	}
+}
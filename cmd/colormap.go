@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/brendan-ward/rastertiler/encoding"
+	"github.com/brendan-ward/rastertiler/gdal"
+)
+
+// maximum number of missing values listed when validating a colormap
+const maxMissingValues = 20
+
+// Get the colormap of single-band dataset d from --colormap or
+// --colormap-file, or from the color table of d if neither is provided and
+// values are encoded as images without rescaling.  Returns nil if there is
+// no colormap.
+func loadColormap(d *gdal.Dataset, rescale *rescaling) (*encoding.Colormap, error) {
+	if d.BandCount() != 1 {
+		return nil, nil
+	}
+
+	switch {
+	case colormapStr != "":
+		return encoding.NewColormap(colormapStr)
+	case colormapFile != "":
+		return encoding.ReadColormap(colormapFile)
+	case rescale != nil || encodingStr != "image":
+		return nil, nil
+	}
+
+	if dtype := d.DType(); dtype != "uint8" && dtype != "uint16" {
+		return nil, nil
+	}
+	colors, labels, err := d.ColorTable()
+	if err != nil || colors == nil {
+		return nil, err
+	}
+
+	fmt.Printf("Using color table of GeoTIFF with %v colors\n", len(colors))
+
+	return encoding.NewColormapFromColorTable(colors, labels)
+}
+
+// Print values of dataset d that are not in categorical colormap, which are
+// rendered as transparent
+func reportMissingValues(d *gdal.Dataset, colormap *encoding.Colormap) error {
+	counts, err := d.ValueCounts(true)
+	if err != nil {
+		return err
+	}
+
+	missing := colormap.Missing(counts)
+	if len(missing) == 0 {
+		return nil
+	}
+
+	var total uint64
+	for _, count := range counts {
+		total += count
+	}
+	fmt.Printf("Warning: %v values of GeoTIFF are not in colormap and will be transparent:\n", len(missing))
+	for i, m := range missing {
+		if i == maxMissingValues {
+			fmt.Printf("  ... and %v more\n", len(missing)-maxMissingValues)
+			break
+		}
+		fmt.Printf("  %v: %v pixels (%.2f%%)\n", m.Value, m.Count, 100*float64(m.Count)/float64(total))
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/brendan-ward/rastertiler/mbtiles"
+	"github.com/brendan-ward/rastertiler/pmtiles"
+	"github.com/brendan-ward/rastertiler/tiles"
+	"github.com/spf13/cobra"
+)
+
+var convertCmd = &cobra.Command{
+	Use:   "convert [IN.mbtiles] [OUT.pmtiles]",
+	Short: "Convert an MBTiles tileset to a PMTiles archive",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 2 {
+			return errors.New("mbtiles and pmtiles filenames are required")
+		}
+		if _, err := os.Stat(args[0]); errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("input file '%s' does not exist", args[0])
+		}
+		if path.Ext(args[0]) != ".mbtiles" {
+			return errors.New("mbtiles filename must end in '.mbtiles'")
+		}
+		outDir, _ := path.Split(args[1])
+		if outDir != "" {
+			if _, err := os.Stat(outDir); errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("output directory '%s' does not exist", outDir)
+			}
+		}
+		if path.Ext(args[1]) != ".pmtiles" {
+			return errors.New("pmtiles filename must end in '.pmtiles'")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return convert(args[0], args[1])
+	},
+	SilenceUsage: true,
+}
+
+func convert(infilename string, outfilename string) error {
+	db, err := mbtiles.NewMBtilesReader(infilename, 1)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	metadata, err := db.ReadMetadata()
+	if err != nil {
+		return err
+	}
+	// tile IDs of pmtiles assume the WebMercatorQuad grid
+	if tms := metadata["tile_matrix_set"]; tms != "" && tms != tiles.WebMercatorQuad.ID {
+		return fmt.Errorf("pmtiles output only supports the WebMercatorQuad tile matrix set, but '%s' uses %v", infilename, tms)
+	}
+
+	w, err := pmtiles.NewPMTilesWriter(outfilename)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	for key, value := range metadata {
+		if err = w.WriteMetadataItem(key, value); err != nil {
+			return err
+		}
+	}
+
+	count := 0
+	err = db.ReadTiles(func(tile *tiles.TileID, data []byte) error {
+		count++
+		return w.WriteTile(tile, data)
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Writing %v tiles to %v\n", count, outfilename)
+
+	return w.Finalize()
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"image/color"
 	"os"
 	"path"
 	"path/filepath"
@@ -10,9 +11,13 @@ import (
 	"sync"
 
 	"github.com/brendan-ward/rastertiler/affine"
+	"github.com/brendan-ward/rastertiler/array"
+	"github.com/brendan-ward/rastertiler/directory"
 	"github.com/brendan-ward/rastertiler/encoding"
+	"github.com/brendan-ward/rastertiler/encoding/webp"
 	"github.com/brendan-ward/rastertiler/gdal"
 	"github.com/brendan-ward/rastertiler/mbtiles"
+	"github.com/brendan-ward/rastertiler/pmtiles"
 	"github.com/brendan-ward/rastertiler/tiles"
 	"github.com/gosuri/uiprogress"
 	"github.com/spf13/cobra"
@@ -26,29 +31,71 @@ var attribution string
 var numWorkers int
 var tileSize int
 var colormapStr string
+var colormapFile string
+var resume bool
+var update bool
+var linkModeStr string
+var pyramid bool
+var reducerStr string
+var rescaleStr string
+var rescaleDtype string
+var resamplingStr string
+var encodingStr string
+var elevationBase float64
+var elevationInterval float64
+var formatStr string
+var quality int
+var lossless bool
+var nodataStr string
+var backgroundStr string
+var background *color.NRGBA
+var tmsStr string
+var inputList string
+var overlapStr string
+var cutlineFile string
+var bboxStr string
+var bbox *affine.Bounds
+var tilesFrom string
+var tileMatrixSet *tiles.TileMatrixSet
+
+// value of encoding metadata item for each elevation encoding, as used by
+// raster-dem sources in MapLibre
+var elevationEncodings = map[string]string{
+	"terrain-rgb": "mapbox",
+	"terrarium":   "terrarium",
+}
 
 var createCmd = &cobra.Command{
-	Use:   "create [IN.tiff] [OUT.mbtiles]",
-	Short: "Create an MBTiles tileset from a single-band GeoTIFF",
+	Use:   "create [IN.tiff...] [OUT.mbtiles|OUT.pmtiles|OUT_DIR]",
+	Short: "Create an MBTiles, PMTiles, or directory tileset from a single-band, RGB, or RGBA GeoTIFF or a mosaic of GeoTIFFs",
 	Args: func(cmd *cobra.Command, args []string) error {
-		if len(args) < 2 {
-			return errors.New("GeoTIFF and mbtiles filenames are required")
-		}
-		if _, err := os.Stat(args[0]); errors.Is(err, os.ErrNotExist) {
-			return fmt.Errorf("input file '%s' does not exist", args[0])
+		if len(args) < 2 && !(len(args) == 1 && inputList != "") {
+			return errors.New("GeoTIFF and mbtiles or pmtiles filename or output directory are required")
 		}
-		outDir, _ := path.Split(args[1])
+		outfilename := args[len(args)-1]
+		outDir, _ := path.Split(outfilename)
 		if outDir != "" {
 			if _, err := os.Stat(outDir); errors.Is(err, os.ErrNotExist) {
 				return fmt.Errorf("output directory '%s' does not exist", outDir)
 			}
 		}
-		if path.Ext(args[1]) != ".mbtiles" {
-			return errors.New("mbtiles filename must end in '.mbtiles'")
+		if ext := path.Ext(outfilename); ext != "" && ext != ".mbtiles" && ext != ".pmtiles" {
+			if info, err := os.Stat(outfilename); err != nil || !info.IsDir() {
+				return errors.New("output filename must end in '.mbtiles' or '.pmtiles'")
+			}
+		}
+		if path.Ext(outfilename) == ".pmtiles" && (resume || update) {
+			return errors.New("resume and update are not supported for pmtiles")
 		}
 		return nil
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
+		outfilename := args[len(args)-1]
+		inputs, err := expandInputs(args[:len(args)-1], inputList)
+		if err != nil {
+			return err
+		}
+
 		// validate flags
 		if numWorkers < 1 {
 			numWorkers = 1
@@ -56,11 +103,92 @@ var createCmd = &cobra.Command{
 		if maxzoom < minzoom {
 			return errors.New("maxzoom must be no smaller than minzoom")
 		}
-		if maxzoom > 25 {
-			return errors.New("maxzoom must be no greater than 24")
+		if resume && update {
+			return errors.New("only one of resume or update may be used")
+		}
+		if pyramid && resume {
+			return errors.New("resume is not supported when creating tiles using a pyramid")
+		}
+		switch encodingStr {
+		case "image":
+		case "terrain-rgb":
+			if !cmd.Flags().Changed("base") {
+				elevationBase = encoding.TerrainRGBBase
+			}
+			if !cmd.Flags().Changed("interval") {
+				elevationInterval = encoding.TerrainRGBInterval
+			}
+		case "terrarium":
+			if !cmd.Flags().Changed("base") {
+				elevationBase = encoding.TerrariumBase
+			}
+			if !cmd.Flags().Changed("interval") {
+				elevationInterval = encoding.TerrariumInterval
+			}
+		default:
+			return fmt.Errorf("encoding must be one of image, terrain-rgb, terrarium: %v", encodingStr)
+		}
+		if encodingStr != "image" && elevationInterval <= 0 {
+			return errors.New("interval must be greater than 0")
+		}
+		switch formatStr {
+		case "png":
+		case "webp", "jpg":
+			if quality < 1 || quality > 100 {
+				return fmt.Errorf("quality must be between 1 and 100: %v", quality)
+			}
+			if encodingStr != "image" && (formatStr == "jpg" || !lossless) {
+				return fmt.Errorf("%v encoding requires png or lossless webp", encodingStr)
+			}
+		default:
+			return fmt.Errorf("format must be one of png, webp, jpg: %v", formatStr)
+		}
+		if colormapStr != "" && colormapFile != "" {
+			return errors.New("only one of colormap or colormap-file may be used")
+		}
+		if backgroundStr != "" {
+			if encodingStr != "image" {
+				return fmt.Errorf("background is not supported for %v encoding", encodingStr)
+			}
+			c, err := encoding.ParseColor(backgroundStr)
+			if err != nil {
+				return fmt.Errorf("invalid background color %q: %v", backgroundStr, err)
+			}
+			background = &c
+		}
+		if ext := path.Ext(outfilename); formatStr == "jpg" && background == nil && ext != ".mbtiles" && ext != ".pmtiles" {
+			// directories use a single file extension for all tiles
+			return errors.New("jpg format without --background is only supported for mbtiles or pmtiles output")
+		}
+		tms, err := loadTileMatrixSet(tmsStr)
+		if err != nil {
+			return err
+		}
+		if err = checkTileMatrixSet(tms, outfilename, maxzoom); err != nil {
+			return err
+		}
+		if pyramid && !tms.IsQuadtree() {
+			return fmt.Errorf("pyramid requires a tile matrix set where each tile is split into 4 tiles at the next zoom level, which %v is not", tms.ID)
+		}
+		tileMatrixSet = tms
+		if overlapStr != "first" && overlapStr != "last" {
+			return fmt.Errorf("overlap must be one of first, last: %v", overlapStr)
+		}
+		if bboxStr != "" {
+			if bbox, err = parseBBox(bboxStr); err != nil {
+				return err
+			}
+			if pyramid && update {
+				// tiles below maxzoom that extend beyond bbox would only be
+				// built from tiles within bbox
+				return errors.New("bbox is not supported when updating using a pyramid")
+			}
+		}
+		if pyramid && tilesFrom != "" {
+			return errors.New("tiles-from is not supported when creating tiles using a pyramid")
 		}
 
-		return create(args[0], args[1])
+		return create(inputs, outfilename)
 	},
 	SilenceUsage: true,
 }
@@ -73,10 +201,173 @@ func init() {
 	createCmd.Flags().StringVarP(&description, "description", "d", "", "tileset description")
 	createCmd.Flags().StringVarP(&attribution, "attribution", "a", "", "tileset description")
 	createCmd.Flags().IntVarP(&numWorkers, "workers", "w", 4, "number of workers to create tiles")
-	createCmd.Flags().StringVarP(&colormapStr, "colormap", "c", "", "colormap '<value>:<hex>,<value>:<hex>'.  Only valid for 8-bit data")
+	createCmd.Flags().StringVarP(&colormapStr, "colormap", "c", "", "colormap '<value>:<color>,<min>-<max>:<color>' for integer data or 'gradient,[rgb|oklab],[clamp|transparent],<value>:<color>,<value>:<color>', where color is hex or a CSS color name.  Only valid for single-band data")
+	createCmd.Flags().StringVar(&colormapFile, "colormap-file", "", "read colormap from a GDAL or QGIS color map text file, SLD (.sld), or JSON (.json) file.  By default, the color table of the GeoTIFF is used if present")
+	createCmd.Flags().BoolVarP(&resume, "resume", "r", false, "resume an interrupted run, skipping tiles already in the mbtiles file")
+	createCmd.Flags().BoolVarP(&update, "update", "u", false, "update an existing mbtiles file or directory, replacing tiles within the zoom range")
+	createCmd.Flags().StringVar(&linkModeStr, "link", "none", "link duplicate tiles when writing to a directory: none, hardlink, symlink")
+	createCmd.Flags().BoolVar(&pyramid, "pyramid", false, "create tiles below maxzoom by downsampling higher zoom tiles instead of reading from the GeoTIFF")
+	createCmd.Flags().StringVar(&reducerStr, "reducer", "nearest", "method used to downsample tiles when using --pyramid: nearest, mode, mean")
+	createCmd.Flags().StringVar(&rescaleStr, "rescale", "", "rescale values to --rescale-dtype: '<min>,<max>' or 'auto' to use min and max from statistics.  Required for float data")
+	createCmd.Flags().StringVar(&resamplingStr, "resampling", "nearest", "resampling used when warping: nearest, bilinear, cubic, cubicspline, lanczos, average, mode, min, max, med, q1, q3.  May be set for zoom ranges, e.g., '0-7:mode,8-:nearest'")
+	createCmd.Flags().StringVar(&encodingStr, "encoding", "image", "tile encoding: image, or terrain-rgb or terrarium for elevation data")
+	createCmd.Flags().Float64Var(&elevationBase, "base", 0, "base elevation of terrain-rgb (default -10000) or terrarium (default -32768) encoding")
+	createCmd.Flags().Float64Var(&elevationInterval, "interval", 0, "elevation interval of terrain-rgb (default 0.1) or terrarium (default 1/256) encoding")
+	createCmd.Flags().StringVar(&formatStr, "format", "png", "tile format: png, webp, or jpg with png for tiles partially covered by data")
+	createCmd.Flags().IntVar(&quality, "quality", 80, "quality of lossy webp or jpg tiles, from 1 to 100")
+	createCmd.Flags().BoolVar(&lossless, "lossless", false, "use lossless webp compression")
+	createCmd.Flags().StringVar(&backgroundStr, "background", "", "hex color used to fill transparent pixels, e.g., '#FFFFFF'")
+	createCmd.Flags().StringVar(&nodataStr, "nodata", "", "override nodata value of GeoTIFF, or 'none' to ignore nodata")
+	createCmd.Flags().StringVar(&rescaleDtype, "rescale-dtype", "uint8", "dtype of rescaled values: uint8, uint16")
+	createCmd.Flags().StringVar(&inputList, "input-list", "", "file listing input GeoTIFFs or glob patterns, one per line, to add to the mosaic of inputs")
+	createCmd.Flags().StringVar(&overlapStr, "overlap", "last", "input used where GeoTIFFs of a mosaic overlap: first or last in order of inputs")
+	createCmd.Flags().StringVar(&cutlineFile, "cutline", "", "clip tiles to the polygons of a GeoJSON or other OGR-readable vector file")
+	createCmd.Flags().StringVar(&bboxStr, "bbox", "", "only create tiles within '<xmin>,<ymin>,<xmax>,<ymax>' in longitude and latitude")
+	createCmd.Flags().StringVar(&tilesFrom, "tiles-from", "", "only create tiles listed as 'z/x/y', one per line, in file, e.g., a list of expired tiles")
+	createCmd.Flags().StringVar(&tmsStr, "tms", tiles.WebMercatorQuad.ID, "tile matrix set: WebMercatorQuad, WorldCRS84Quad, or an OGC TileMatrixSet 2.0 JSON file")
+}
+
+// Get the metadata that must match that of an existing tileset to update
+// it, so that all tiles use the same tile matrix set, tile size, format, and
+// encoding
+func updateMetadata() map[string]string {
+	return map[string]string{
+		"encoding":        elevationEncodings[encodingStr],
+		"format":          metadataFormat(),
+		"tile_matrix_set": metadataTileMatrixSet(),
+		"tilesize":        fmt.Sprint(tileSize),
+	}
+}
+
+// Get the metadata that must match that of an existing tileset to resume
+// it, so that tiles are also not mixed from different sources or colormaps.
+// Overlap is empty unless source is a mosaic.
+func resumeMetadata(source string, overlap string) map[string]string {
+	expected := updateMetadata()
+	expected["source"] = source
+	expected["overlap"] = overlap
+	expected["colormap"] = colormapStr
+	expected["colormap_file"] = colormapFile
+	expected["cutline"] = cutlineFile
+	expected["rescale"] = rescaleStr
+	expected["nodata"] = nodataStr
+	expected["background"] = backgroundStr
+	return expected
+}
+
+// Verify that the metadata of an existing tileset matches expected metadata
+// that would be written for this run; mode is resume or update
+func checkExistingMetadata(db tiles.UpdatableTileWriter, expected map[string]string, mode string) error {
+	metadata, err := db.ReadMetadata()
+	if err != nil {
+		return err
+	}
+
+	for key, value := range expected {
+		if metadata[key] != value {
+			return fmt.Errorf("cannot %s: %s of existing tileset '%s' does not match '%s'", mode, key, metadata[key], value)
+		}
+	}
+
+	return nil
+}
+
+// Format recorded in metadata for jpg tilesets, where tiles partially covered
+// by data are png to retain transparency
+const mixedFormat = "jpg+png"
+
+// Get the format of tiles recorded in metadata according to the format and
+// background options
+func metadataFormat() string {
+	if formatStr == "jpg" && background == nil {
+		return mixedFormat
+	}
+	return formatStr
+}
+
+// tileEncoder encodes tiles according to how they are covered by data
+type tileEncoder struct {
+	encoder encoding.TileEncoder
+	partial encoding.TileEncoder // used for partially covered tiles
+}
+
+// Encode buffer and mask of a tile with coverage
+func (e *tileEncoder) Encode(buffer interface{}, mask []uint8, coverage gdal.Coverage) ([]byte, error) {
+	if coverage == gdal.PartiallyCovered {
+		return e.partial.Encode(buffer, mask)
+	}
+	return e.encoder.Encode(buffer, mask)
+}
+
+// Create the encoder for tiles with dtype, bands, and nodata according to
+// the encoding and format options
+func newTileEncoder(dtype string, bands int, nodata interface{}, colormap *encoding.Colormap) (*tileEncoder, error) {
+	var encoder encoding.PNGEncoder
+	var err error
+	switch encodingStr {
+	case "terrain-rgb", "terrarium":
+		// base and interval default to those of the encoding
+		encoder = encoding.NewElevationEncoder(tileSize, tileSize, elevationBase, elevationInterval, nodata)
+	default:
+		encoder, err = encoding.NewEncoder(dtype, bands, tileSize, tileSize, colormap, nodata)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if background != nil {
+		encoder = encoding.NewBackgroundEncoder(encoder, tileSize, tileSize, *background)
+	}
+
+	switch formatStr {
+	case "webp":
+		webpEncoder, err := webp.NewWebPEncoder(encoder, lossless, quality)
+		if err != nil {
+			return nil, err
+		}
+		return &tileEncoder{encoder: webpEncoder, partial: webpEncoder}, nil
+	case "jpg":
+		jpegEncoder, err := encoding.NewJPEGEncoder(encoder, quality)
+		if err != nil {
+			return nil, err
+		}
+		if background != nil {
+			// tiles are opaque
+			return &tileEncoder{encoder: jpegEncoder, partial: jpegEncoder}, nil
+		}
+		return &tileEncoder{encoder: jpegEncoder, partial: encoder}, nil
+	default:
+		return &tileEncoder{encoder: encoder, partial: encoder}, nil
+	}
+}
+
+// Open a TileWriter for an mbtiles or pmtiles file or else a directory.  If
+// existing is true, tiles and metadata already in the output are kept.
+func openTileWriter(outfilename string, existing bool) (tiles.TileWriter, error) {
+	switch path.Ext(outfilename) {
+	case ".mbtiles":
+		if existing {
+			return mbtiles.OpenMBtilesWriter(outfilename, numWorkers)
+		}
+		return mbtiles.NewMBtilesWriter(outfilename, numWorkers)
+	case ".pmtiles":
+		return pmtiles.NewPMTilesWriter(outfilename)
+	}
+
+	linkMode, err := directory.ParseLinkMode(linkModeStr)
+	if err != nil {
+		return nil, err
+	}
+	if existing {
+		return directory.OpenDirectoryWriter(outfilename, linkMode)
+	}
+	return directory.NewDirectoryWriter(outfilename, linkMode)
 }
 
-func produce(minZoom uint8, maxZoom uint8, bounds *affine.Bounds, queue chan<- *tiles.TileID) {
+// Add tiles of tms within bounds to queue, except for tiles in existing and
+// tiles outside cutline, if provided, which is in the CRS of tms.  If
+// tileList is provided, only its tiles at each zoom level are added.
+func produce(tms *tiles.TileMatrixSet, minZoom uint8, maxZoom uint8, bounds *affine.Bounds, cutline *gdal.Cutline, tileList map[uint8][]*tiles.TileID, existing map[tiles.TileID]bool, queue chan<- *tiles.TileID) {
 	defer close(queue)
 
 	fmt.Println("Creating tiles")
@@ -84,124 +375,344 @@ func produce(minZoom uint8, maxZoom uint8, bounds *affine.Bounds, queue chan<- *
 	uiprogress.Start()
 
 	for zoom := minZoom; zoom <= maxZoom; zoom++ {
-		minTile, maxTile := tiles.TileRange(zoom, bounds)
+		minTile, maxTile := tms.TileRange(zoom, bounds)
+		count := int(maxTile.X-minTile.X+1) * int(maxTile.Y-minTile.Y+1)
+		if tileList != nil {
+			count = len(tileList[zoom])
+			if count == 0 {
+				continue
+			}
+		}
 		z := zoom
-		count := (maxTile.X - minTile.X + 1) * (maxTile.Y - minTile.Y + 1)
-		bar := uiprogress.AddBar(int(count)).AppendCompleted().PrependElapsed()
+		bar := uiprogress.AddBar(count).AppendCompleted().PrependElapsed()
 		bar.PrependFunc(func(b *uiprogress.Bar) string {
 			return fmt.Sprintf("zoom %2v (%8v/%8v)", z, b.Current(), count)
 		})
 
+		add := func(tileID *tiles.TileID) {
+			if !existing[*tileID] && (cutline == nil || cutline.Intersects(tms.Bounds(tileID))) {
+				queue <- tileID
+			}
+			bar.Incr()
+		}
+
+		if tileList != nil {
+			for _, tileID := range tileList[zoom] {
+				if tileID.X < minTile.X || tileID.X > maxTile.X || tileID.Y < minTile.Y || tileID.Y > maxTile.Y {
+					bar.Incr()
+					continue
+				}
+				add(tileID)
+			}
+			continue
+		}
+
 		for x := minTile.X; x <= maxTile.X; x++ {
 			for y := minTile.Y; y <= maxTile.Y; y++ {
-				queue <- &tiles.TileID{Zoom: zoom, X: x, Y: y}
-				bar.Incr()
+				add(tiles.NewTileID(zoom, x, y))
 			}
 		}
 	}
 	uiprogress.Stop()
 }
 
-func create(infilename string, outfilename string) error {
-	// default to input filename, without extension
+func create(inputs []string, outfilename string) error {
+	// default to first input filename, without extension
 	if tilesetName == "" {
-		tilesetName = strings.TrimSuffix(path.Base(infilename), filepath.Ext(infilename))
+		tilesetName = strings.TrimSuffix(path.Base(inputs[0]), filepath.Ext(inputs[0]))
+	}
+
+	// tiles are created from a mosaic VRT of multiple inputs
+	infilename := inputs[0]
+	overlap := ""
+	if len(inputs) > 1 {
+		if err := checkMosaicInputs(inputs, nodataStr); err != nil {
+			return err
+		}
+		fmt.Printf("Building mosaic of %v GeoTIFFs\n", len(inputs))
+		vrt, err := buildMosaic(inputs, nodataStr, overlapStr)
+		if err != nil {
+			return err
+		}
+		defer gdal.RemoveFile(vrt)
+		infilename = vrt
+		overlap = overlapStr
 	}
 
-	d, err := gdal.Open(infilename)
+	d, err := openDataset(infilename, nodataStr)
 	if err != nil {
 		return err
 	}
 	defer d.Close()
 
-	var colormap *encoding.Colormap
-	if d.DType() == "uint8" && colormapStr != "" {
-		colormap, err = encoding.NewColormap(colormapStr)
-		if err != nil {
+	if d.BandCount() != 1 && d.BandCount() != 3 && d.BandCount() != 4 {
+		return fmt.Errorf("only 1-band, 3-band (RGB), or 4-band (RGBA) GeoTIFFs are supported, got %v bands", d.BandCount())
+	}
+
+	rescale, err := parseRescaling(rescaleStr, rescaleDtype, d)
+	if err != nil {
+		return err
+	}
+
+	colormap, err := loadColormap(d, rescale)
+	if err != nil {
+		return err
+	}
+	isGradient := colormap != nil && colormap.Gradient() != nil
+
+	isElevation := encodingStr != "image"
+	if isElevation && (d.BandCount() != 1 || colormap != nil || rescale != nil) {
+		return fmt.Errorf("%v encoding is only valid for single-band data without a colormap or rescale", encodingStr)
+	}
+
+	// dtype of tiles after rescaling
+	dtype := d.DType()
+	if rescale != nil {
+		dtype = rescale.dtype
+	} else if (dtype == "float32" || dtype == "float64") && !isGradient && !isElevation {
+		return fmt.Errorf("%v data must be rescaled using --rescale or rendered using a gradient colormap", dtype)
+	}
+	if colormap != nil && !isGradient && (dtype == "float32" || dtype == "float64") {
+		return errors.New("categorical colormap is only valid for integer data")
+	}
+	if colormap != nil && !isGradient && rescale == nil && (dtype == "uint8" || dtype == "uint16") {
+		if err = reportMissingValues(d, colormap); err != nil {
+			return err
+		}
+	}
+
+	resamplings, err := parseResamplingRanges(resamplingStr)
+	if err != nil {
+		return err
+	}
+
+	reducer, err := array.ParseReducer(reducerStr)
+	if err != nil {
+		return err
+	}
+
+	var tileList map[uint8][]*tiles.TileID
+	if tilesFrom != "" {
+		if tileList, err = readTileList(tilesFrom, minzoom, maxzoom); err != nil {
 			return err
 		}
 	}
 
-	db, err := mbtiles.NewMBtilesWriter(outfilename, numWorkers)
+	sources := make([]string, len(inputs))
+	for i, input := range inputs {
+		if sources[i], err = filepath.Abs(input); err != nil {
+			return err
+		}
+	}
+	source := strings.Join(sources, ",")
+
+	var existing map[tiles.TileID]bool
+	_, statErr := os.Stat(outfilename)
+	isExisting := (resume || update) && statErr == nil
+
+	db, err := openTileWriter(outfilename, isExisting)
 	if err != nil {
 		return err
 	}
 	defer db.Close()
 
+	// only existing tilesets are updatable
+	updatable, _ := db.(tiles.UpdatableTileWriter)
+
+	if update && isExisting {
+		if err = checkExistingMetadata(updatable, updateMetadata(), "update"); err != nil {
+			return err
+		}
+	}
+
+	if resume && isExisting {
+		if err = checkExistingMetadata(updatable, resumeMetadata(source, overlap), "resume"); err != nil {
+			return err
+		}
+
+		existing, err = updatable.ReadTileIDs()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Resuming: %v tiles already created\n", len(existing))
+	}
+
 	geoBounds, err := d.GeoBounds()
 	if err != nil {
 		return err
 	}
 
-	mercatorBounds, err := d.MercatorBounds()
+	// bounds in the CRS of the tile matrix set
+	tmsBounds, err := d.TransformBounds(tileMatrixSet.CRS)
 	if err != nil {
 		return err
 	}
 
+	// tiles are limited to bbox, if provided
+	if bbox != nil {
+		bboxTMS, err := bboxToTileMatrixSet(bbox)
+		if err != nil {
+			return err
+		}
+		geoBounds = geoBounds.Intersection(bbox)
+		tmsBounds = tmsBounds.Intersection(bboxTMS)
+		if geoBounds == nil || tmsBounds == nil {
+			return errors.New("bbox does not intersect the GeoTIFF")
+		}
+	}
+
+	// tiles are clipped to the cutline, which is used in the CRS of the tile
+	// matrix set, and bounds are limited to its extent
+	var cutline *gdal.Cutline
+	if cutlineFile != "" {
+		if cutline, geoBounds, tmsBounds, err = readCutline(cutlineFile, geoBounds, tmsBounds); err != nil {
+			return err
+		}
+		defer cutline.Close()
+	}
+
 	d.Close()
 
-	db.WriteMetadata(tilesetName, description, attribution, minzoom, maxzoom, geoBounds)
+	// zoom levels and bounds are merged with those of an existing tileset
+	if err = db.WriteMetadata(tilesetName, description, attribution, minzoom, maxzoom, geoBounds, metadataFormat()); err != nil {
+		return err
+	}
+
+	// record how tileset was created so that an interrupted run can be resumed
+	if err = db.WriteMetadataItem("source", source); err != nil {
+		return err
+	}
+	if overlap != "" {
+		if err = db.WriteMetadataItem("overlap", overlap); err != nil {
+			return err
+		}
+	}
+	if colormapStr != "" {
+		if err = db.WriteMetadataItem("colormap", colormapStr); err != nil {
+			return err
+		}
+	}
+	if colormapFile != "" {
+		if err = db.WriteMetadataItem("colormap_file", colormapFile); err != nil {
+			return err
+		}
+	}
+	if cutlineFile != "" {
+		if err = db.WriteMetadataItem("cutline", cutlineFile); err != nil {
+			return err
+		}
+	}
+	if colormap != nil && colormap.HasLabels() {
+		legend, err := colormap.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		if err = db.WriteMetadataItem("legend", string(legend)); err != nil {
+			return err
+		}
+	}
+	if rescaleStr != "" {
+		if err = db.WriteMetadataItem("rescale", rescaleStr); err != nil {
+			return err
+		}
+	}
+	if nodataStr != "" {
+		if err = db.WriteMetadataItem("nodata", nodataStr); err != nil {
+			return err
+		}
+	}
+	if backgroundStr != "" {
+		if err = db.WriteMetadataItem("background", backgroundStr); err != nil {
+			return err
+		}
+	}
+	if err = db.WriteMetadataItem("tilesize", fmt.Sprint(tileSize)); err != nil {
+		return err
+	}
+	if tms := metadataTileMatrixSet(); tms != "" {
+		if err = db.WriteMetadataItem("tile_matrix_set", tms); err != nil {
+			return err
+		}
+		if err = db.WriteMetadataItem("crs", tileMatrixSet.CRS); err != nil {
+			return err
+		}
+	}
+	if isElevation {
+		if err = db.WriteMetadataItem("encoding", elevationEncodings[encodingStr]); err != nil {
+			return err
+		}
+	}
+
+	if pyramid {
+		if !isExisting {
+			updatable = nil
+		}
+		if err = createPyramid(infilename, db, updatable, tmsBounds, cutline, colormap, rescale, resamplings.forZoom(maxzoom), reducer); err != nil {
+			return err
+		}
+		return db.Finalize()
+	}
 
 	queue := make(chan *tiles.TileID)
 	var wg sync.WaitGroup
 
-	go produce(minzoom, maxzoom, mercatorBounds, queue)
+	go produce(tileMatrixSet, minzoom, maxzoom, tmsBounds, cutline, tileList, existing, queue)
 
 	for i := 0; i < numWorkers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 
-			var buffer interface{}
-			var tileTransform affine.Affine
-
-			con, err := db.GetConnection()
+			ds, err := openDataset(infilename, nodataStr)
 			if err != nil {
 				panic(err)
 			}
-			defer db.CloseConnection(con)
-
-			// get VRT once per goroutine
-			ds, err := gdal.Open(infilename)
 			defer ds.Close()
 
-			vrt, err := ds.GetWarpedVRT("EPSG:3857")
-			if err != nil {
-				panic(err)
-			}
-			defer vrt.Close()
-
-			var encoder encoding.PNGEncoder
-			switch ds.DType() {
-			case "uint8":
-				buffer = make([]uint8, tileSize*tileSize, tileSize*tileSize)
-
-				if colormap != nil {
-					encoder = encoding.NewColormapEncoder(tileSize, tileSize, colormap)
-				} else {
-					encoder = encoding.NewGrayscaleEncoder(tileSize, tileSize)
+			// get VRT for each resampling once per goroutine
+			readers := make(map[gdal.Resampling]*tileReader)
+			for zoom := minzoom; zoom <= maxzoom; zoom++ {
+				resampling := resamplings.forZoom(zoom)
+				if _, ok := readers[resampling]; ok {
+					continue
 				}
-			// TODO: uint16
-			case "uint32":
-				buffer = make([]uint32, tileSize*tileSize, tileSize*tileSize)
-				encoder = encoding.NewRGBEncoder(tileSize, tileSize)
+				vrt, err := ds.GetWarpedVRT(tileMatrixSet.CRS, resampling, cutline)
+				if err != nil {
+					panic(err)
+				}
+				defer vrt.Close()
 
-			default:
-				panic(fmt.Sprintf("encoding not yet supported for other dtypes: %v", ds.DType()))
+				readers[resampling] = newTileReader(vrt, tileMatrixSet, tileSize, rescale)
+			}
+
+			// all readers have the same dtype, bands, and nodata
+			reader := readers[resamplings.forZoom(minzoom)]
+			buffer := reader.NewBuffer()
+			mask := reader.NewMask()
+			encoder, err := newTileEncoder(reader.dtype, reader.bands, reader.nodata, colormap)
+			if err != nil {
+				panic(err)
 			}
 
 			for tileID := range queue {
-				hasData, err := vrt.ReadTile(buffer, &tileTransform, tileID, tileSize)
+				coverage, err := readers[resamplings.forZoom(tileID.Zoom)].Read(buffer, mask, tileID)
 				if err != nil {
 					panic(err)
 				}
 
-				if hasData {
-					png, err := encoder.Encode(buffer)
+				if coverage != gdal.NotCovered {
+					data, err := encoder.Encode(buffer, mask, coverage)
 					if err != nil {
 						panic(err)
 					}
-					mbtiles.WriteTile(con, tileID, png)
+					if err = db.WriteTile(tileID, data); err != nil {
+						panic(err)
+					}
+				} else if isExisting {
+					// remove tile that may have been previously written
+					if err = updatable.DeleteTile(tileID); err != nil {
+						panic(err)
+					}
 				}
 			}
 		}()
@@ -209,7 +720,5 @@ func create(infilename string, outfilename string) error {
 
 	wg.Wait()
 
-	db.CreateIndexes()
-
-	return nil
+	return db.Finalize()
 }
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/brendan-ward/rastertiler/affine"
+	"github.com/brendan-ward/rastertiler/gdal"
+)
+
+// Read cutline from filename and project it to the CRS of the tile matrix
+// set.  Returns the cutline along with geographic and tile matrix set bounds
+// limited to the extent of the cutline.
+func readCutline(filename string, geoBounds *affine.Bounds, tmsBounds *affine.Bounds) (*gdal.Cutline, *affine.Bounds, *affine.Bounds, error) {
+	cutline, err := gdal.ReadCutline(filename)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer cutline.Close()
+
+	geoCutline, err := cutline.Transform("EPSG:4326")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	geoBounds = geoBounds.Intersection(geoCutline.Bounds())
+	geoCutline.Close()
+
+	tmsCutline, err := cutline.Transform(tileMatrixSet.CRS)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	tmsBounds = tmsBounds.Intersection(tmsCutline.Bounds())
+
+	if geoBounds == nil || tmsBounds == nil {
+		tmsCutline.Close()
+		return nil, nil, nil, errors.New("cutline does not intersect the GeoTIFF")
+	}
+
+	return tmsCutline, geoBounds, tmsBounds, nil
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/brendan-ward/rastertiler/affine"
+	"github.com/brendan-ward/rastertiler/gdal"
+	"github.com/brendan-ward/rastertiler/tiles"
+)
+
+// Parse bbox option, which is '<xmin>,<ymin>,<xmax>,<ymax>' in longitude and
+// latitude
+func parseBBox(value string) (*affine.Bounds, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("bbox must be '<xmin>,<ymin>,<xmax>,<ymax>': %v", value)
+	}
+	var coords [4]float64
+	for i, part := range parts {
+		coord, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bbox coordinate: %q", part)
+		}
+		coords[i] = coord
+	}
+	bounds := &affine.Bounds{Xmin: coords[0], Ymin: coords[1], Xmax: coords[2], Ymax: coords[3]}
+	if bounds.Xmin < -180 || bounds.Xmax > 180 || bounds.Ymin < -90 || bounds.Ymax > 90 {
+		return nil, fmt.Errorf("bbox must be within -180,-90,180,90: %v", value)
+	}
+	if bounds.Xmax <= bounds.Xmin || bounds.Ymax <= bounds.Ymin {
+		return nil, fmt.Errorf("bbox max must be greater than min: %v", value)
+	}
+	return bounds, nil
+}
+
+// Project bbox from longitude and latitude to the CRS of the tile matrix set
+func bboxToTileMatrixSet(bbox *affine.Bounds) (*affine.Bounds, error) {
+	if tileMatrixSet != tiles.WebMercatorQuad {
+		return gdal.TransformGeoBounds(bbox, tileMatrixSet.CRS)
+	}
+	bounds := &affine.Bounds{}
+	bounds.Xmin, bounds.Ymin = tiles.GeoToMercator(bbox.Xmin, bbox.Ymin)
+	bounds.Xmax, bounds.Ymax = tiles.GeoToMercator(bbox.Xmax, bbox.Ymax)
+	return bounds, nil
+}
+
+// Read tiles listed as 'z/x/y', one per line, from filename, such as a list
+// of expired tiles.  Blank lines and lines starting with '#' are ignored, as
+// are tiles outside the range of zoom levels.  Returns the unique tiles at
+// each zoom level, in the order listed.
+func readTileList(filename string, minZoom uint8, maxZoom uint8) (map[uint8][]*tiles.TileID, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tileList := make(map[uint8][]*tiles.TileID)
+	seen := make(map[tiles.TileID]bool)
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		tile, err := tiles.ParseTileID(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tile on line %v of '%s': %v", lineNum, filename, err)
+		}
+		if tile.Zoom < minZoom || tile.Zoom > maxZoom || seen[*tile] {
+			continue
+		}
+		if !tileMatrixSet.Contains(tile) {
+			return nil, fmt.Errorf("tile %v on line %v of '%s' is outside tile matrix set %v", line, lineNum, filename, tileMatrixSet.ID)
+		}
+		seen[*tile] = true
+		tileList[tile.Zoom] = append(tileList[tile.Zoom], tile)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return tileList, nil
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/brendan-ward/rastertiler/affine"
+	"github.com/brendan-ward/rastertiler/tiles"
+)
+
+func TestParseBBox(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected affine.Bounds
+	}{
+		{value: "-10,-5,10,5", expected: affine.Bounds{Xmin: -10, Ymin: -5, Xmax: 10, Ymax: 5}},
+		{value: " -105.1, 39.6 ,-104.6,40 ", expected: affine.Bounds{Xmin: -105.1, Ymin: 39.6, Xmax: -104.6, Ymax: 40}},
+		{value: "-180,-90,180,90", expected: affine.Bounds{Xmin: -180, Ymin: -90, Xmax: 180, Ymax: 90}},
+	}
+
+	for _, tc := range tests {
+		bounds, err := parseBBox(tc.value)
+		if err != nil {
+			t.Errorf("%q: %v", tc.value, err)
+			continue
+		}
+		if *bounds != tc.expected {
+			t.Errorf("%q: %v not expected value: %v", tc.value, bounds, tc.expected)
+		}
+	}
+
+	for _, value := range []string{"", "1,2,3", "1,2,3,4,5", "a,0,1,1", "10,0,-10,5", "0,5,1,5", "-181,0,0,1", "0,0,181,1", "0,-91,1,1", "0,0,1,91"} {
+		if _, err := parseBBox(value); err == nil {
+			t.Errorf("parseBBox() did not return error for %q", value)
+		}
+	}
+}
+
+func TestBBoxToTileMatrixSet(t *testing.T) {
+	defer func(tms *tiles.TileMatrixSet) { tileMatrixSet = tms }(tileMatrixSet)
+	tileMatrixSet = tiles.WebMercatorQuad
+
+	// latitude is truncated to the extent of Web Mercator
+	bounds, err := bboxToTileMatrixSet(&affine.Bounds{Xmin: -180, Ymin: -90, Xmax: 0, Ymax: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	minTile, maxTile := tileMatrixSet.TileRange(1, bounds)
+	if *minTile != (tiles.TileID{Zoom: 1, X: 0, Y: 1}) || *maxTile != (tiles.TileID{Zoom: 1, X: 0, Y: 1}) {
+		t.Errorf("tile range %v - %v not expected value: 1/0/1", minTile, maxTile)
+	}
+}
+
+func TestReadTileList(t *testing.T) {
+	defer func(tms *tiles.TileMatrixSet) { tileMatrixSet = tms }(tileMatrixSet)
+
+	write := func(content string) string {
+		filename := filepath.Join(t.TempDir(), "tiles.txt")
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return filename
+	}
+
+	// comments, blank lines, duplicates, and tiles outside zoom range are
+	// skipped
+	tileMatrixSet = tiles.WebMercatorQuad
+	filename := write("# expired tiles\n2/1/3\n\n 3/7/0 \n2/1/3\n0/0/0\n5/1/1\n3/2/2\n")
+	tileList, err := readTileList(filename, 1, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[uint8][]tiles.TileID{
+		2: {{Zoom: 2, X: 1, Y: 3}},
+		3: {{Zoom: 3, X: 7, Y: 0}, {Zoom: 3, X: 2, Y: 2}},
+	}
+	if len(tileList) != len(expected) {
+		t.Fatalf("%v not expected value: %v", tileList, expected)
+	}
+	for zoom, expectedTiles := range expected {
+		if len(tileList[zoom]) != len(expectedTiles) {
+			t.Errorf("zoom %v: %v not expected value: %v", zoom, tileList[zoom], expectedTiles)
+			continue
+		}
+		for i, tile := range expectedTiles {
+			if *tileList[zoom][i] != tile {
+				t.Errorf("zoom %v: %v not expected value: %v", zoom, tileList[zoom][i], tile)
+			}
+		}
+	}
+
+	// WorldCRS84Quad has twice as many columns as rows
+	tileMatrixSet = tiles.WorldCRS84Quad
+	if _, err = readTileList(write("1/3/1\n"), 0, 4); err != nil {
+		t.Errorf("tile within WorldCRS84Quad returned error: %v", err)
+	}
+
+	errorTests := []struct {
+		tms     *tiles.TileMatrixSet
+		content string
+	}{
+		{tms: tiles.WebMercatorQuad, content: "1/2/0\n"},
+		{tms: tiles.WebMercatorQuad, content: "1/0/2\n"},
+		{tms: tiles.WorldCRS84Quad, content: "1/4/0\n"},
+		{tms: tiles.WebMercatorQuad, content: "1/0\n"},
+		{tms: tiles.WebMercatorQuad, content: "1/0/0.png\n"},
+	}
+	for _, tc := range errorTests {
+		tileMatrixSet = tc.tms
+		if _, err = readTileList(write(tc.content), 0, 4); err == nil {
+			t.Errorf("%v: readTileList() did not return error for %q", tc.tms.ID, tc.content)
+		}
+	}
+
+	if _, err = readTileList(filepath.Join(t.TempDir(), "missing.txt"), 0, 4); err == nil {
+		t.Errorf("readTileList() did not return error for missing file")
+	}
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/brendan-ward/rastertiler/array"
+	"github.com/brendan-ward/rastertiler/gdal"
+)
+
+// Expand input arguments, which are filenames or glob patterns, followed by
+// filenames listed one per line in listFilename, if provided.  Matches of a
+// glob pattern are in lexical order.
+func expandInputs(args []string, listFilename string) ([]string, error) {
+	patterns := args
+	if listFilename != "" {
+		f, err := os.Open(listFilename)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line != "" && !strings.HasPrefix(line, "#") {
+				patterns = append(patterns, line)
+			}
+		}
+		if err = scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	var inputs []string
+	for _, pattern := range patterns {
+		if !strings.ContainsAny(pattern, "*?[") {
+			if _, err := os.Stat(pattern); errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("input file '%s' does not exist", pattern)
+			}
+			inputs = append(inputs, pattern)
+			continue
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid input pattern '%s': %v", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no input files match '%s'", pattern)
+		}
+		inputs = append(inputs, matches...)
+	}
+
+	if len(inputs) == 0 {
+		return nil, errors.New("at least one input GeoTIFF is required")
+	}
+	return inputs, nil
+}
+
+// Verify that inputs have the same number of bands, dtype, and CRS, and the
+// same nodata unless nodata is overridden
+func checkMosaicInputs(inputs []string, nodata string) error {
+	first, err := gdal.Open(inputs[0])
+	if err != nil {
+		return err
+	}
+	defer first.Close()
+
+	for _, input := range inputs[1:] {
+		d, err := gdal.Open(input)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.BandCount() != first.BandCount():
+			err = fmt.Errorf("number of bands of '%s' (%v) does not match '%s' (%v)", input, d.BandCount(), inputs[0], first.BandCount())
+		case d.DType() != first.DType():
+			err = fmt.Errorf("dtype of '%s' (%v) does not match '%s' (%v)", input, d.DType(), inputs[0], first.DType())
+		case nodata == "" && !sameNodata(d.Nodata(), first.Nodata()):
+			err = fmt.Errorf("nodata of '%s' (%v) does not match '%s' (%v); use --nodata to override", input, d.Nodata(), inputs[0], first.Nodata())
+		case !d.SameCRS(first):
+			err = fmt.Errorf("CRS of '%s' does not match '%s'", input, inputs[0])
+		}
+		d.Close()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Return true if both nodata values are unset or equal, including NaN
+func sameNodata(a interface{}, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	x := array.ToFloat(a)
+	y := array.ToFloat(b)
+	return x == y || (math.IsNaN(x) && math.IsNaN(y))
+}
+
+// Build an in-memory mosaic VRT of inputs at the highest resolution of the
+// inputs.  Where inputs overlap, values are taken from the first input if
+// overlap is "first", otherwise from the last input.  If nodata is not empty,
+// it overrides the nodata of all inputs; see openDataset().  Returns the
+// filename of the VRT, which must be removed using gdal.RemoveFile().
+func buildMosaic(inputs []string, nodata string, overlap string) (string, error) {
+	options := []string{"-resolution", "highest"}
+	switch nodata {
+	case "":
+	case "none":
+		options = append(options, "-srcnodata", "None", "-vrtnodata", "None")
+	default:
+		options = append(options, "-srcnodata", nodata, "-vrtnodata", nodata)
+	}
+
+	// later sources are drawn over earlier sources
+	sources := inputs
+	if overlap == "first" {
+		sources = make([]string, len(inputs))
+		for i, input := range inputs {
+			sources[len(inputs)-1-i] = input
+		}
+	}
+
+	return gdal.BuildVRT(sources, options)
+}
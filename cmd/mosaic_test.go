@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpandInputs(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.tif", "a.tif", "c.tif", "other.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+
+	listFilename := path("inputs.txt")
+	list := "# inputs\n\n  " + path("c.tif") + "  \n" + path("[ab].tif") + "\n"
+	if err := os.WriteFile(listFilename, []byte(list), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args     []string
+		list     string
+		expected []string
+	}{
+		{args: []string{path("b.tif")}, expected: []string{path("b.tif")}},
+		// glob matches are in lexical order, after preceding arguments
+		{args: []string{path("c.tif"), path("*.tif")}, expected: []string{path("c.tif"), path("a.tif"), path("b.tif"), path("c.tif")}},
+		// list file entries follow arguments
+		{args: []string{path("b.tif")}, list: listFilename, expected: []string{path("b.tif"), path("c.tif"), path("a.tif"), path("b.tif")}},
+		{list: listFilename, expected: []string{path("c.tif"), path("a.tif"), path("b.tif")}},
+	}
+
+	for _, tc := range tests {
+		inputs, err := expandInputs(tc.args, tc.list)
+		if err != nil {
+			t.Errorf("%v, %q: %v", tc.args, tc.list, err)
+			continue
+		}
+		if strings.Join(inputs, ",") != strings.Join(tc.expected, ",") {
+			t.Errorf("%v, %q: %v not expected value: %v", tc.args, tc.list, inputs, tc.expected)
+		}
+	}
+
+	errorTests := []struct {
+		args []string
+		list string
+	}{
+		{args: nil},
+		{args: []string{path("missing.tif")}},
+		{args: []string{path("*.png")}},
+		{args: []string{path("[.tif")}},
+		{list: path("missing.txt")},
+	}
+	for _, tc := range errorTests {
+		if _, err := expandInputs(tc.args, tc.list); err == nil {
+			t.Errorf("expandInputs() did not return error for %v, %q", tc.args, tc.list)
+		}
+	}
+}
+
+func TestSameNodata(t *testing.T) {
+	nan := math.NaN()
+	tests := []struct {
+		a        interface{}
+		b        interface{}
+		expected bool
+	}{
+		{a: nil, b: nil, expected: true},
+		{a: nil, b: uint8(0), expected: false},
+		{a: uint8(0), b: nil, expected: false},
+		{a: uint8(0), b: uint8(0), expected: true},
+		{a: uint8(0), b: uint8(255), expected: false},
+		{a: int16(-9999), b: int16(-9999), expected: true},
+		{a: float32(nan), b: float32(nan), expected: true},
+		{a: float64(nan), b: float32(nan), expected: true},
+		{a: float32(nan), b: float32(0), expected: false},
+		{a: float32(-3.4e38), b: float32(-3.4e38), expected: true},
+	}
+
+	for _, tc := range tests {
+		if value := sameNodata(tc.a, tc.b); value != tc.expected {
+			t.Errorf("sameNodata(%v, %v): %v not expected value: %v", tc.a, tc.b, value, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package cmd
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/brendan-ward/rastertiler/affine"
+	"github.com/brendan-ward/rastertiler/array"
+	"github.com/brendan-ward/rastertiler/encoding"
+	"github.com/brendan-ward/rastertiler/gdal"
+	"github.com/brendan-ward/rastertiler/tiles"
+	"github.com/gosuri/uiprogress"
+)
+
+// pyramidBuilder renders tiles at the maximum zoom level from the source,
+// and builds tiles at lower zoom levels by downsampling the raw buffers of
+// their four child tiles
+type pyramidBuilder struct {
+	minZoom   uint8
+	maxZoom   uint8
+	tileSize  int
+	dtype     string
+	bands     int
+	fill      interface{} // value of pixels without data: nodata, or 0 if not set
+	reducer   array.Reducer
+	ranges    map[uint8][2]*tiles.TileID
+	cutline   *gdal.Cutline // optional, in CRS of tileMatrixSet
+	bars      map[uint8]*uiprogress.Bar
+	writer    tiles.TileWriter
+	updatable tiles.UpdatableTileWriter // only set when updating existing tileset
+}
+
+// Return true if tile is within the range of tiles at its zoom level and
+// intersects the cutline, if provided
+func (p *pyramidBuilder) inRange(tile *tiles.TileID) bool {
+	r := p.ranges[tile.Zoom]
+	if tile.X < r[0].X || tile.X > r[1].X || tile.Y < r[0].Y || tile.Y > r[1].Y {
+		return false
+	}
+	return p.cutline == nil || p.cutline.Intersects(tileMatrixSet.Bounds(tile))
+}
+
+// Increment progress bars for tile and all of its descendants that are within
+// the range of tiles at their zoom levels, when they are skipped because tile
+// is outside the cutline
+func (p *pyramidBuilder) skip(tile *tiles.TileID) {
+	for zoom := tile.Zoom; zoom <= p.maxZoom; zoom++ {
+		shift := zoom - tile.Zoom
+		r := p.ranges[zoom]
+		xmin := maxUint64(uint64(tile.X)<<shift, uint64(r[0].X))
+		xmax := minUint64((uint64(tile.X+1)<<shift)-1, uint64(r[1].X))
+		ymin := maxUint64(uint64(tile.Y)<<shift, uint64(r[0].Y))
+		ymax := minUint64((uint64(tile.Y+1)<<shift)-1, uint64(r[1].Y))
+		if xmin > xmax || ymin > ymax {
+			// descendants are outside the range
+			return
+		}
+		for i := uint64(0); i < (xmax-xmin+1)*(ymax-ymin+1); i++ {
+			p.bars[zoom].Incr()
+		}
+	}
+}
+
+func minUint64(a uint64, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxUint64(a uint64, b uint64) uint64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// pyramidTile is the raw buffer and mask of a tile and how it is covered by
+// data; buffer is nil if tile does not have data
+type pyramidTile struct {
+	buffer   interface{}
+	mask     []uint8
+	coverage gdal.Coverage
+}
+
+// Combine the raw buffers and masks of four child tiles, ordered upper left,
+// upper right, lower left, lower right, into a buffer and mask for their
+// parent tile.  Children without data have nil buffers.  Only pixels that are
+// valid according to the mask of each child are used, and a pixel of the
+// parent is valid if any of its child pixels are valid.  Parent is only fully
+// covered if all children are fully covered.
+func (p *pyramidBuilder) combine(children [4]pyramidTile) pyramidTile {
+	var buffer interface{}
+	var mask []uint8
+	half := p.tileSize / 2
+	bandSize := p.tileSize * p.tileSize
+
+	coverage := gdal.FullyCovered
+	for i, tile := range children {
+		if tile.coverage != gdal.FullyCovered {
+			coverage = gdal.PartiallyCovered
+		}
+		child := tile.buffer
+		if child == nil {
+			continue
+		}
+		if buffer == nil {
+			buffer = array.NewBuffer(p.dtype, bandSize*p.bands)
+			array.Fill(buffer, p.fill)
+			mask = make([]uint8, bandSize)
+		}
+
+		// downsample each band separately
+		for band := 0; band < p.bands; band++ {
+			start := band * bandSize
+			array.Downsample(array.Slice(buffer, start, start+bandSize), array.Slice(child, start, start+bandSize), tile.mask, p.tileSize, (i/2)*half, (i%2)*half, p.fill, p.reducer)
+		}
+		array.DownsampleMask(mask, tile.mask, p.tileSize, (i/2)*half, (i%2)*half)
+	}
+
+	// the mask of 4-band data is its alpha band, so transparent tiles do not
+	// have data
+	if buffer == nil || array.AllEquals(mask, uint8(0)) {
+		return pyramidTile{}
+	}
+	return pyramidTile{buffer: buffer, mask: mask, coverage: coverage}
+}
+
+// Encode and write tile if it has data, otherwise remove any tile previously
+// written to an existing tileset
+func (p *pyramidBuilder) write(encoder *tileEncoder, tile *tiles.TileID, t pyramidTile) error {
+	defer p.bars[tile.Zoom].Incr()
+
+	if t.buffer != nil {
+		data, err := encoder.Encode(t.buffer, t.mask, t.coverage)
+		if err != nil {
+			return err
+		}
+		return p.writer.WriteTile(tile, data)
+	}
+	if p.updatable != nil {
+		return p.updatable.DeleteTile(tile)
+	}
+	return nil
+}
+
+// Recursively build tile and all of its descendants up to the maximum zoom
+// level, and return the raw buffer of tile, which is nil if it does not have
+// data
+func (p *pyramidBuilder) build(reader *tileReader, encoder *tileEncoder, tile *tiles.TileID) (pyramidTile, error) {
+	if !p.inRange(tile) {
+		p.skip(tile)
+		return pyramidTile{}, nil
+	}
+
+	var t pyramidTile
+	if tile.Zoom == p.maxZoom {
+		buffer := reader.NewBuffer()
+		mask := reader.NewMask()
+		coverage, err := reader.Read(buffer, mask, tile)
+		if err != nil {
+			return pyramidTile{}, err
+		}
+		if coverage != gdal.NotCovered {
+			t = pyramidTile{buffer: buffer, mask: mask, coverage: coverage}
+		}
+	} else {
+		var children [4]pyramidTile
+		for i := 0; i < 4; i++ {
+			child := tiles.NewTileID(tile.Zoom+1, 2*tile.X+uint32(i%2), 2*tile.Y+uint32(i/2))
+			childTile, err := p.build(reader, encoder, child)
+			if err != nil {
+				return pyramidTile{}, err
+			}
+			children[i] = childTile
+		}
+		t = p.combine(children)
+	}
+
+	if err := p.write(encoder, tile, t); err != nil {
+		return pyramidTile{}, err
+	}
+
+	return t, nil
+}
+
+// Create tiles using a pyramid: tiles at the maximum zoom level are read
+// from the source using resampling and lower zoom levels are built by
+// downsampling using reducer.
+//
+// Tiles at a split zoom level, chosen so that there are enough tiles for all
+// workers, are built in parallel, each along with all of its descendants.
+// Raw buffers are only retained for tiles at the split zoom level, which are
+// then used to build the remaining lower zoom levels.
+func createPyramid(infilename string, db tiles.TileWriter, updatable tiles.UpdatableTileWriter, bounds *affine.Bounds, cutline *gdal.Cutline, colormap *encoding.Colormap, rescale *rescaling, resampling gdal.Resampling, reducer array.Reducer) error {
+	p := &pyramidBuilder{
+		minZoom:   minzoom,
+		maxZoom:   maxzoom,
+		tileSize:  tileSize,
+		reducer:   reducer,
+		ranges:    make(map[uint8][2]*tiles.TileID),
+		cutline:   cutline,
+		bars:      make(map[uint8]*uiprogress.Bar),
+		writer:    db,
+		updatable: updatable,
+	}
+
+	// get VRT once per worker
+	readers := make([]*tileReader, numWorkers)
+	for i := 0; i < numWorkers; i++ {
+		ds, err := openDataset(infilename, nodataStr)
+		if err != nil {
+			return err
+		}
+		defer ds.Close()
+
+		vrt, err := ds.GetWarpedVRT(tileMatrixSet.CRS, resampling, cutline)
+		if err != nil {
+			return err
+		}
+		defer vrt.Close()
+
+		readers[i] = newTileReader(vrt, tileMatrixSet, tileSize, rescale)
+	}
+	p.dtype = readers[0].dtype
+	p.bands = readers[0].bands
+	nodata := readers[0].nodata
+	p.fill = nodata
+	if p.fill == nil {
+		p.fill = array.ZeroValue(p.dtype)
+	}
+
+	fmt.Println("Creating tiles")
+
+	uiprogress.Start()
+	defer uiprogress.Stop()
+
+	splitZoom := maxzoom
+	for zoom := minzoom; zoom <= maxzoom; zoom++ {
+		minTile, maxTile := tileMatrixSet.TileRange(zoom, bounds)
+		p.ranges[zoom] = [2]*tiles.TileID{minTile, maxTile}
+
+		z := zoom
+		count := (maxTile.X - minTile.X + 1) * (maxTile.Y - minTile.Y + 1)
+		bar := uiprogress.AddBar(int(count)).AppendCompleted().PrependElapsed()
+		bar.PrependFunc(func(b *uiprogress.Bar) string {
+			return fmt.Sprintf("zoom %2v (%8v/%8v)", z, b.Current(), count)
+		})
+		p.bars[zoom] = bar
+
+		if splitZoom == maxzoom && int(count) >= 4*numWorkers {
+			splitZoom = zoom
+		}
+	}
+
+	queue := make(chan *tiles.TileID)
+	var mu sync.Mutex
+	buffers := make(map[tiles.TileID]pyramidTile)
+	var wg sync.WaitGroup
+
+	go func() {
+		defer close(queue)
+		r := p.ranges[splitZoom]
+		for x := r[0].X; x <= r[1].X; x++ {
+			for y := r[0].Y; y <= r[1].Y; y++ {
+				queue <- tiles.NewTileID(splitZoom, x, y)
+			}
+		}
+	}()
+
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func(reader *tileReader) {
+			defer wg.Done()
+
+			encoder, err := newTileEncoder(p.dtype, p.bands, nodata, colormap)
+			if err != nil {
+				panic(err)
+			}
+
+			for tileID := range queue {
+				t, err := p.build(reader, encoder, tileID)
+				if err != nil {
+					panic(err)
+				}
+				if t.buffer != nil && splitZoom > minzoom {
+					mu.Lock()
+					buffers[*tileID] = t
+					mu.Unlock()
+				}
+			}
+		}(readers[i])
+	}
+
+	wg.Wait()
+
+	// build remaining zoom levels from the buffers of the split zoom level
+	encoder, err := newTileEncoder(p.dtype, p.bands, nodata, colormap)
+	if err != nil {
+		return err
+	}
+
+	for zoom := int(splitZoom) - 1; zoom >= int(minzoom); zoom-- {
+		parentBuffers := make(map[tiles.TileID]pyramidTile)
+		r := p.ranges[uint8(zoom)]
+		for x := r[0].X; x <= r[1].X; x++ {
+			for y := r[0].Y; y <= r[1].Y; y++ {
+				tile := tiles.NewTileID(uint8(zoom), x, y)
+
+				var children [4]pyramidTile
+				for i := 0; i < 4; i++ {
+					children[i] = buffers[tiles.TileID{Zoom: tile.Zoom + 1, X: 2*x + uint32(i%2), Y: 2*y + uint32(i/2)}]
+				}
+				t := p.combine(children)
+
+				if err = p.write(encoder, tile, t); err != nil {
+					return err
+				}
+				if t.buffer != nil {
+					parentBuffers[*tile] = t
+				}
+			}
+		}
+		buffers = parentBuffers
+	}
+
+	return nil
+}
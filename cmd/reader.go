@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/brendan-ward/rastertiler/affine"
+	"github.com/brendan-ward/rastertiler/array"
+	"github.com/brendan-ward/rastertiler/gdal"
+	"github.com/brendan-ward/rastertiler/tiles"
+)
+
+// Open dataset from filename.  If nodata is not empty, it overrides the nodata
+// value of all bands, or removes nodata if "none".
+func openDataset(filename string, nodata string) (*gdal.Dataset, error) {
+	d, err := gdal.Open(filename)
+	if err != nil || nodata == "" {
+		return d, err
+	}
+
+	var value interface{}
+	if nodata != "none" {
+		rawValue, err := strconv.ParseFloat(nodata, 64)
+		if err != nil {
+			d.Close()
+			return nil, fmt.Errorf("invalid nodata: %q", err)
+		}
+		value, err = array.FromFloat(rawValue, d.DType())
+		if err != nil {
+			d.Close()
+			return nil, fmt.Errorf("invalid nodata: %q", err)
+		}
+	}
+
+	// the VRT reads from d, and closes it when closed
+	vrt, err := d.WithNodata(value)
+	if err != nil {
+		d.Close()
+		return nil, err
+	}
+	return vrt, nil
+}
+
+// rescaling linearly maps values between min and max to dtype
+type rescaling struct {
+	min   float64
+	max   float64
+	dtype string
+}
+
+// Parse rescale option "<min>,<max>" or "auto" to use the minimum and
+// maximum of the dataset from its statistics.  Returns nil if value is empty.
+func parseRescaling(value string, dtype string, d *gdal.Dataset) (*rescaling, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if dtype != "uint8" && dtype != "uint16" {
+		return nil, fmt.Errorf("rescale dtype must be uint8 or uint16: %v", dtype)
+	}
+	if d.BandCount() != 1 {
+		return nil, errors.New("rescale is only supported for single-band data")
+	}
+
+	if value == "auto" {
+		min, max, err := d.Statistics(true)
+		if err != nil {
+			return nil, err
+		}
+		if max <= min {
+			return nil, fmt.Errorf("cannot rescale: all values of dataset are %v", min)
+		}
+		return &rescaling{min: min, max: max, dtype: dtype}, nil
+	}
+
+	parts := strings.Split(value, ",")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("rescale must be 'auto' or '<min>,<max>': %v", value)
+	}
+	min, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rescale min: %q", err)
+	}
+	max, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rescale max: %q", err)
+	}
+	if max <= min {
+		return nil, errors.New("rescale max must be greater than min")
+	}
+
+	return &rescaling{min: min, max: max, dtype: dtype}, nil
+}
+
+// tileReader reads tiles of a tile matrix set from a VRT warped to the CRS
+// of the tile matrix set, rescaling values if needed
+type tileReader struct {
+	vrt      *gdal.Dataset
+	tms      *tiles.TileMatrixSet
+	tileSize int
+	bands    int
+	dtype    string      // dtype of tiles returned by Read()
+	nodata   interface{} // nodata of tiles returned by Read()
+	rescale  *rescaling  // optional
+	raw      interface{} // buffer to read values before rescaling
+}
+
+func newTileReader(vrt *gdal.Dataset, tms *tiles.TileMatrixSet, tileSize int, rescale *rescaling) *tileReader {
+	r := &tileReader{
+		vrt:      vrt,
+		tms:      tms,
+		tileSize: tileSize,
+		bands:    vrt.BandCount(),
+		dtype:    vrt.DType(),
+		nodata:   vrt.Nodata(),
+		rescale:  rescale,
+	}
+	if rescale != nil {
+		// rescaled values are at least 1, 0 is nodata
+		r.dtype = rescale.dtype
+		r.nodata = array.ZeroValue(rescale.dtype)
+		r.raw = array.NewBuffer(vrt.DType(), tileSize*tileSize*r.bands)
+	}
+	return r
+}
+
+// Create a buffer of the correct dtype and size for Read()
+func (r *tileReader) NewBuffer() interface{} {
+	return array.NewBuffer(r.dtype, r.tileSize*r.tileSize*r.bands)
+}
+
+// Create a mask of the correct size for Read()
+func (r *tileReader) NewMask() []uint8 {
+	return make([]uint8, r.tileSize*r.tileSize)
+}
+
+// Read tile into buffer and its mask into mask; returns how tile is covered
+// by data
+func (r *tileReader) Read(buffer interface{}, mask []uint8, tileID *tiles.TileID) (gdal.Coverage, error) {
+	var tileTransform affine.Affine
+
+	if r.rescale == nil {
+		return r.vrt.ReadTile(buffer, mask, &tileTransform, r.tms, tileID, r.tileSize)
+	}
+
+	coverage, err := r.vrt.ReadTile(r.raw, mask, &tileTransform, r.tms, tileID, r.tileSize)
+	if err != nil || coverage == gdal.NotCovered {
+		return gdal.NotCovered, err
+	}
+	if err = array.Rescale(buffer, r.raw, r.rescale.min, r.rescale.max, r.vrt.Nodata()); err != nil {
+		return gdal.NotCovered, err
+	}
+	return coverage, nil
+}
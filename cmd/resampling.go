@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/brendan-ward/rastertiler/gdal"
+)
+
+// zoomResampling is the resampling used for a range of zoom levels
+type zoomResampling struct {
+	minZoom    uint8
+	maxZoom    uint8
+	resampling gdal.Resampling
+}
+
+// resamplingRanges are the resampling algorithms used for ranges of zoom
+// levels, falling back to a default for zoom levels not in any range
+type resamplingRanges struct {
+	fallback gdal.Resampling
+	ranges   []zoomResampling
+}
+
+// Parse resampling option, which is a comma-delimited set of <resampling>
+// or <minzoom>-<maxzoom>:<resampling> entries, e.g., "mode" or
+// "0-7:mode,8-:nearest".  Either zoom level may be omitted to leave the
+// range open, and a single zoom level may be used instead of a range.  The
+// first matching range is used for a zoom level; an entry without a range
+// sets the default for all other zoom levels (nearest if not provided).
+func parseResamplingRanges(value string) (*resamplingRanges, error) {
+	r := &resamplingRanges{fallback: gdal.Nearest}
+
+	for _, entry := range strings.Split(strings.ReplaceAll(value, " ", ""), ",") {
+		parts := strings.Split(entry, ":")
+		if len(parts) == 1 {
+			resampling, err := gdal.ParseResampling(parts[0])
+			if err != nil {
+				return nil, err
+			}
+			r.fallback = resampling
+			continue
+		}
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid resampling entry: %v", entry)
+		}
+
+		resampling, err := gdal.ParseResampling(parts[1])
+		if err != nil {
+			return nil, err
+		}
+
+		zooms := strings.Split(parts[0], "-")
+		if len(zooms) > 2 {
+			return nil, fmt.Errorf("invalid zoom range: %v", parts[0])
+		}
+		if len(zooms) == 1 {
+			// single zoom level
+			zooms = append(zooms, zooms[0])
+		}
+
+		zr := zoomResampling{minZoom: 0, maxZoom: 255, resampling: resampling}
+		if zooms[0] != "" {
+			zoom, err := strconv.ParseUint(zooms[0], 10, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid zoom range: %v", parts[0])
+			}
+			zr.minZoom = uint8(zoom)
+		}
+		if zooms[1] != "" {
+			zoom, err := strconv.ParseUint(zooms[1], 10, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid zoom range: %v", parts[0])
+			}
+			zr.maxZoom = uint8(zoom)
+		}
+		if zr.maxZoom < zr.minZoom {
+			return nil, fmt.Errorf("invalid zoom range: %v", parts[0])
+		}
+
+		r.ranges = append(r.ranges, zr)
+	}
+
+	return r, nil
+}
+
+// Get the resampling used for zoom
+func (r *resamplingRanges) forZoom(zoom uint8) gdal.Resampling {
+	for _, zr := range r.ranges {
+		if zoom >= zr.minZoom && zoom <= zr.maxZoom {
+			return zr.resampling
+		}
+	}
+	return r.fallback
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/brendan-ward/rastertiler/gdal"
+)
+
+func TestParseResamplingRanges(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected map[uint8]gdal.Resampling
+	}{
+		{value: "nearest", expected: map[uint8]gdal.Resampling{0: gdal.Nearest, 10: gdal.Nearest, 255: gdal.Nearest}},
+		{value: "mode", expected: map[uint8]gdal.Resampling{0: gdal.Mode, 24: gdal.Mode}},
+		// open-ended ranges with default nearest
+		{value: "0-7:mode,8-:bilinear", expected: map[uint8]gdal.Resampling{0: gdal.Mode, 7: gdal.Mode, 8: gdal.Bilinear, 255: gdal.Bilinear}},
+		{value: "-3:average", expected: map[uint8]gdal.Resampling{0: gdal.Average, 3: gdal.Average, 4: gdal.Nearest}},
+		{value: "5-:cubic", expected: map[uint8]gdal.Resampling{4: gdal.Nearest, 5: gdal.Cubic, 20: gdal.Cubic}},
+		// single zoom level and fallback in any position
+		{value: "lanczos,4:mode", expected: map[uint8]gdal.Resampling{3: gdal.Lanczos, 4: gdal.Mode, 5: gdal.Lanczos}},
+		// first matching range is used
+		{value: "0-10:mode,5-15:average", expected: map[uint8]gdal.Resampling{5: gdal.Mode, 10: gdal.Mode, 11: gdal.Average, 16: gdal.Nearest}},
+		// spaces and case are ignored
+		{value: "0-2: Mode, 3-:Q3", expected: map[uint8]gdal.Resampling{2: gdal.Mode, 3: gdal.Q3}},
+	}
+
+	for _, tc := range tests {
+		r, err := parseResamplingRanges(tc.value)
+		if err != nil {
+			t.Errorf("%q: %v", tc.value, err)
+			continue
+		}
+		for zoom, expected := range tc.expected {
+			if resampling := r.forZoom(zoom); resampling != expected {
+				t.Errorf("%q: resampling %v at zoom %v not expected value: %v", tc.value, resampling, zoom, expected)
+			}
+		}
+	}
+
+	for _, value := range []string{"", "foo", "0-7:foo", "0-7-9:mode", "a-7:mode", "0-b:mode", "8-7:mode", "0-256:mode", "0:mode:nearest"} {
+		if _, err := parseResamplingRanges(value); err == nil {
+			t.Errorf("parseResamplingRanges() did not return error for %q", value)
+		}
+	}
+}
@@ -19,4 +19,6 @@ func Execute() error {
 
 func init() {
 	rootCmd.AddCommand(createCmd)
+	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(convertCmd)
 }
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+
+	"github.com/brendan-ward/rastertiler/server"
+	"github.com/spf13/cobra"
+)
+
+// flags are stored separately from those of create, because cobra sets
+// defaults when flags are registered
+var host string
+var port int
+var cacheSize int
+var serveWorkers int
+var serveMinzoom uint8
+var serveMaxzoom uint8
+var serveTileSize int
+var serveColormapStr string
+
+var serveCmd = &cobra.Command{
+	Use:   "serve [IN.mbtiles|IN.tif...]",
+	Short: "Serve tiles from MBTiles tilesets or render tiles on demand from GeoTIFFs",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("at least one mbtiles or GeoTIFF filename is required")
+		}
+		for _, filename := range args {
+			if _, err := os.Stat(filename); errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("input file '%s' does not exist", filename)
+			}
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// validate flags
+		if serveWorkers < 1 {
+			serveWorkers = 1
+		}
+		if serveMaxzoom < serveMinzoom {
+			return errors.New("maxzoom must be no smaller than minzoom")
+		}
+		if cacheSize < 0 {
+			return errors.New("cache size must be no smaller than 0")
+		}
+
+		return serve(args)
+	},
+	SilenceUsage: true,
+}
+
+func init() {
+	serveCmd.Flags().StringVarP(&host, "host", "H", "localhost", "host name or IP address to listen on")
+	serveCmd.Flags().IntVarP(&port, "port", "p", 8000, "port to listen on")
+	serveCmd.Flags().IntVarP(&serveWorkers, "workers", "w", 4, "number of connections per tileset")
+	serveCmd.Flags().Uint8VarP(&serveMinzoom, "minzoom", "Z", 0, "minimum zoom level of GeoTIFF tilesets")
+	serveCmd.Flags().Uint8VarP(&serveMaxzoom, "maxzoom", "z", 22, "maximum zoom level of GeoTIFF tilesets")
+	serveCmd.Flags().IntVarP(&serveTileSize, "tilesize", "s", 512, "default tile size in pixels of GeoTIFF tilesets")
+	serveCmd.Flags().StringVarP(&serveColormapStr, "colormap", "c", "", "default colormap of GeoTIFF tilesets '<value>:<color>,<min>-<max>:<color>' for integer data or 'gradient,...'; see create")
+	serveCmd.Flags().IntVar(&cacheSize, "cache-size", 256, "maximum size in MB of tiles rendered from GeoTIFFs to keep in memory")
+}
+
+func serve(filenames []string) error {
+	s := server.NewServer(cacheSize * 1024 * 1024)
+	defer s.Close()
+
+	for _, filename := range filenames {
+		var err error
+		switch path.Ext(filename) {
+		case ".mbtiles":
+			err = s.AddMBTiles(filename, serveWorkers)
+		case ".tif", ".tiff":
+			err = s.AddGeoTIFF(filename, serveWorkers, serveTileSize, serveColormapStr, serveMinzoom, serveMaxzoom)
+		default:
+			err = fmt.Errorf("filename '%s' must end in '.mbtiles', '.tif', or '.tiff'", filename)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	address := fmt.Sprintf("%s:%d", host, port)
+	for _, name := range s.Names() {
+		fmt.Printf("Serving %v at http://%v/%v.json\n", name, address, name)
+	}
+
+	return http.ListenAndServe(address, s)
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/brendan-ward/rastertiler/tiles"
+)
+
+// Get a built-in tile matrix set by ID or else read it from an OGC
+// TileMatrixSet JSON file
+func loadTileMatrixSet(value string) (*tiles.TileMatrixSet, error) {
+	if tms, ok := tiles.TileMatrixSets[value]; ok {
+		return tms, nil
+	}
+	tms, err := tiles.ReadTileMatrixSet(value)
+	if err != nil {
+		return nil, fmt.Errorf("tile matrix set must be WebMercatorQuad, WorldCRS84Quad, or a TileMatrixSet JSON file: %v", err)
+	}
+	return tms, nil
+}
+
+// Verify that tiles of tms up to maxZoom can be written to outfilename
+func checkTileMatrixSet(tms *tiles.TileMatrixSet, outfilename string, maxZoom uint8) error {
+	if maxZoom > tms.MaxZoom() {
+		return fmt.Errorf("maxzoom must be no greater than %v for tile matrix set %v", tms.MaxZoom(), tms.ID)
+	}
+
+	switch path.Ext(outfilename) {
+	case ".pmtiles":
+		if tms != tiles.WebMercatorQuad {
+			return errors.New("pmtiles output only supports the WebMercatorQuad tile matrix set")
+		}
+	case ".mbtiles":
+		// mbtiles rows are numbered from the bottom of a grid of 2^zoom rows
+		for zoom := uint8(0); zoom <= maxZoom; zoom++ {
+			if uint64(tms.Matrix(zoom).MatrixHeight) > uint64(1)<<zoom {
+				return fmt.Errorf("mbtiles output requires no more than 2^zoom rows of tiles at each zoom level, but tile matrix set %v has %v rows at zoom %v", tms.ID, tms.Matrix(zoom).MatrixHeight, zoom)
+			}
+		}
+	}
+	return nil
+}
+
+// Get the tile matrix set recorded in metadata; empty for WebMercatorQuad,
+// which is the default for tilesets
+func metadataTileMatrixSet() string {
+	if tileMatrixSet == tiles.WebMercatorQuad {
+		return ""
+	}
+	return tileMatrixSet.ID
+}
@@ -0,0 +1,366 @@
+package directory
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/brendan-ward/rastertiler/affine"
+	"github.com/brendan-ward/rastertiler/tilejson"
+	"github.com/brendan-ward/rastertiler/tiles"
+)
+
+const metadataFilename = "metadata.json"
+const tileJSONFilename = "tiles.json"
+
+// images are stored here by hash when using symbolic links
+const imagesDir = "images"
+
+// LinkMode determines how duplicate tile images are written
+type LinkMode int
+
+const (
+	// write each tile as a separate file
+	NoLink LinkMode = iota
+	// hard link duplicate tiles to the first tile written with the same image
+	HardLink
+	// write each unique image once to the images directory and symbolically
+	// link all tiles to it
+	SymLink
+)
+
+func ParseLinkMode(mode string) (LinkMode, error) {
+	switch mode {
+	case "", "none":
+		return NoLink, nil
+	case "hardlink":
+		return HardLink, nil
+	case "symlink":
+		return SymLink, nil
+	default:
+		return NoLink, fmt.Errorf("link mode must be one of none, hardlink, symlink: %v", mode)
+	}
+}
+
+// DirectoryWriter writes tiles to a directory as {z}/{x}/{y}.{format} files,
+// along with metadata.json containing the same metadata that is stored in an
+// mbtiles file, and tiles.json containing TileJSON.
+type DirectoryWriter struct {
+	path     string
+	format   string // file extension of tiles, set from metadata
+	link     LinkMode
+	existing bool // true if opened from an existing directory
+
+	mu       sync.Mutex
+	metadata map[string]string
+	images   map[string]string // tile image hash to path of file written with that image
+}
+
+// Create a new DirectoryWriter.  The directory must not already exist or
+// must be empty.
+func NewDirectoryWriter(path string, link LinkMode) (*DirectoryWriter, error) {
+	entries, err := os.ReadDir(path)
+	if err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("output directory '%s' is not empty", path)
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	if err = os.MkdirAll(path, 0755); err != nil {
+		return nil, fmt.Errorf("could not create output directory: %q", err)
+	}
+
+	return &DirectoryWriter{
+		path:     path,
+		format:   "png",
+		link:     link,
+		metadata: make(map[string]string),
+		images:   make(map[string]string),
+	}, nil
+}
+
+// Open an existing tile directory for writing, keeping all tiles and
+// metadata that were previously written to it
+func OpenDirectoryWriter(path string, link LinkMode) (*DirectoryWriter, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("output directory '%s' does not exist", path)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("output path '%s' is not a directory", path)
+	}
+
+	metadata := make(map[string]string)
+	data, err := os.ReadFile(filepath.Join(path, metadataFilename))
+	if err == nil {
+		if err = json.Unmarshal(data, &metadata); err != nil {
+			return nil, fmt.Errorf("could not read metadata: %q", err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	format := metadata["format"]
+	if format == "" {
+		format = "png"
+	}
+
+	return &DirectoryWriter{
+		path:     path,
+		format:   format,
+		link:     link,
+		existing: true,
+		metadata: metadata,
+		images:   make(map[string]string),
+	}, nil
+}
+
+func (w *DirectoryWriter) Close() {}
+
+// Write metadata.json and tiles.json; caller must hold lock
+func (w *DirectoryWriter) writeMetadataFiles() error {
+	data, err := json.MarshalIndent(w.metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(filepath.Join(w.path, metadataFilename), data, 0644); err != nil {
+		return fmt.Errorf("could not write metadata: %q", err)
+	}
+
+	tj := tilejson.FromMetadata(w.metadata, fmt.Sprintf("{z}/{x}/{y}.%s", w.format))
+	data, err = json.MarshalIndent(tj, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(filepath.Join(w.path, tileJSONFilename), data, 0644); err != nil {
+		return fmt.Errorf("could not write TileJSON: %q", err)
+	}
+
+	return nil
+}
+
+// Write metadata; format is also used as the file extension of tiles, so
+// this must be called before writing tiles
+func (w *DirectoryWriter) WriteMetadata(name string, description string, attribution string, minZoom uint8, maxZoom uint8, bounds *affine.Bounds, format string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.format = format
+
+	// merge with metadata of any tiles previously written to this tileset
+	minZoom, maxZoom, bounds = tilejson.MergeMetadata(w.metadata, minZoom, maxZoom, bounds)
+
+	for key, value := range tilejson.NewMetadata(name, description, attribution, minZoom, maxZoom, bounds, format) {
+		w.metadata[key] = value
+	}
+
+	return w.writeMetadataFiles()
+}
+
+func (w *DirectoryWriter) WriteMetadataItem(key string, value interface{}) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.metadata[key] = fmt.Sprint(value)
+
+	return w.writeMetadataFiles()
+}
+
+func (w *DirectoryWriter) ReadMetadata() (map[string]string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	metadata := make(map[string]string, len(w.metadata))
+	for key, value := range w.metadata {
+		metadata[key] = value
+	}
+	return metadata, nil
+}
+
+func (w *DirectoryWriter) tilePath(tile *tiles.TileID) string {
+	return filepath.Join(w.path, strconv.Itoa(int(tile.Zoom)), strconv.Itoa(int(tile.X)), fmt.Sprintf("%v.%s", tile.Y, w.format))
+}
+
+// Write the tile to {z}/{x}/{y}.{format}, replacing any existing tile
+func (w *DirectoryWriter) WriteTile(tile *tiles.TileID, data []byte) error {
+	path := w.tilePath(tile)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("could not write tile %v: %q", tile, err)
+	}
+
+	// always remove existing file first, so that content shared with other
+	// tiles by a link is not modified
+	if err := removeIfExists(path); err != nil {
+		return fmt.Errorf("could not write tile %v: %q", tile, err)
+	}
+
+	if w.link == NoLink {
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return fmt.Errorf("could not write tile %v: %q", tile, err)
+		}
+		return nil
+	}
+
+	h := sha1.New()
+	h.Write(data)
+	id := hex.EncodeToString(h.Sum(nil))
+
+	w.mu.Lock()
+	imagePath, ok := w.images[id]
+	w.mu.Unlock()
+
+	if !ok {
+		imagePath = path
+		if w.link == SymLink {
+			imagePath = filepath.Join(w.path, imagesDir, fmt.Sprintf("%s.%s", id, w.format))
+			if err := os.MkdirAll(filepath.Dir(imagePath), 0755); err != nil {
+				return fmt.Errorf("could not write tile %v: %q", tile, err)
+			}
+		}
+
+		// Note: another goroutine may write the same image at the same time;
+		// only the first to finish is used for links
+		if err := os.WriteFile(imagePath, data, 0644); err != nil {
+			return fmt.Errorf("could not write tile %v: %q", tile, err)
+		}
+
+		w.mu.Lock()
+		if existingPath, ok := w.images[id]; ok {
+			imagePath = existingPath
+		} else {
+			w.images[id] = imagePath
+		}
+		w.mu.Unlock()
+
+		if imagePath == path {
+			return nil
+		}
+	}
+
+	var err error
+	switch w.link {
+	case HardLink:
+		err = os.Link(imagePath, path)
+	case SymLink:
+		var target string
+		target, err = filepath.Rel(filepath.Dir(path), imagePath)
+		if err == nil {
+			err = os.Symlink(target, path)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("could not link tile %v: %q", tile, err)
+	}
+
+	return nil
+}
+
+// Delete the tile, if present
+func (w *DirectoryWriter) DeleteTile(tile *tiles.TileID) error {
+	if err := removeIfExists(w.tilePath(tile)); err != nil {
+		return fmt.Errorf("could not delete tile %v: %q", tile, err)
+	}
+	return nil
+}
+
+// Read the IDs of all tiles already written to the directory
+func (w *DirectoryWriter) ReadTileIDs() (map[tiles.TileID]bool, error) {
+	tileIDs := make(map[tiles.TileID]bool)
+
+	err := w.walkTiles(func(tileID tiles.TileID, path string) error {
+		tileIDs[tileID] = true
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not read existing tiles: %q", err)
+	}
+
+	return tileIDs, nil
+}
+
+// Call fn for every {z}/{x}/{y} tile file in the directory
+func (w *DirectoryWriter) walkTiles(fn func(tileID tiles.TileID, path string) error) error {
+	return filepath.WalkDir(w.path, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		relPath, err := filepath.Rel(w.path, path)
+		if err != nil {
+			return err
+		}
+		parts := strings.Split(filepath.ToSlash(relPath), "/")
+		if len(parts) != 3 || filepath.Ext(parts[2]) != "."+w.format {
+			return nil
+		}
+
+		z, err := strconv.ParseUint(parts[0], 10, 8)
+		if err != nil {
+			return nil
+		}
+		x, err := strconv.ParseUint(parts[1], 10, 32)
+		if err != nil {
+			return nil
+		}
+		y, err := strconv.ParseUint(strings.TrimSuffix(parts[2], "."+w.format), 10, 32)
+		if err != nil {
+			return nil
+		}
+
+		return fn(tiles.TileID{Zoom: uint8(z), X: uint32(x), Y: uint32(y)}, path)
+	})
+}
+
+// Remove images that are no longer linked from any tile, if tiles may have
+// been replaced in an existing directory using symbolic links
+func (w *DirectoryWriter) Finalize() error {
+	if !w.existing || w.link != SymLink {
+		return nil
+	}
+
+	used := make(map[string]bool)
+	err := w.walkTiles(func(tileID tiles.TileID, path string) error {
+		target, err := os.Readlink(path)
+		if err == nil {
+			used[filepath.Clean(filepath.Join(filepath.Dir(path), target))] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("could not remove unused images: %q", err)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(w.path, imagesDir))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("could not remove unused images: %q", err)
+	}
+	for _, entry := range entries {
+		path := filepath.Join(w.path, imagesDir, entry.Name())
+		if !used[path] {
+			if err = os.Remove(path); err != nil {
+				return fmt.Errorf("could not remove unused images: %q", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func removeIfExists(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
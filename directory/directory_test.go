@@ -0,0 +1,100 @@
+package directory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/brendan-ward/rastertiler/affine"
+	"github.com/brendan-ward/rastertiler/tiles"
+)
+
+func TestDirectoryWriterFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tiles")
+	w, err := NewDirectoryWriter(path, NoLink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.WriteMetadata("test", "", "", 0, 2, &affine.Bounds{Xmin: -10, Ymin: -10, Xmax: 10, Ymax: 10}, "webp"); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.WriteTile(tiles.NewTileID(2, 1, 0), []byte("abc")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(filepath.Join(path, "2", "1", "0.webp")); err != nil {
+		t.Errorf("tile not written using extension of format: %v", err)
+	}
+
+	// reopened directory uses format from metadata
+	w, err = OpenDirectoryWriter(path, NoLink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	existing, err := w.ReadTileIDs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(existing) != 1 {
+		t.Errorf("%v not expected tiles", existing)
+	}
+}
+
+func TestDirectoryWriter(t *testing.T) {
+	for _, link := range []LinkMode{NoLink, HardLink, SymLink} {
+		path := filepath.Join(t.TempDir(), "tiles")
+		w, err := NewDirectoryWriter(path, link)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err = w.WriteMetadata("test", "", "", 0, 2, &affine.Bounds{Xmin: -10, Ymin: -10, Xmax: 10, Ymax: 10}, "png"); err != nil {
+			t.Fatal(err)
+		}
+
+		// tiles 1 and 2 have the same image
+		tileIDs := []*tiles.TileID{tiles.NewTileID(2, 1, 0), tiles.NewTileID(2, 1, 1), tiles.NewTileID(2, 2, 1)}
+		images := []string{"abc", "abc", "def"}
+		for i, tileID := range tileIDs {
+			if err = w.WriteTile(tileID, []byte(images[i])); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for i, tileID := range tileIDs {
+			data, err := os.ReadFile(w.tilePath(tileID))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != images[i] {
+				t.Errorf("link mode %v: tile %v: %v not expected value: %v", link, tileID, string(data), images[i])
+			}
+		}
+
+		// replacing a linked tile must not modify other tiles
+		if err = w.WriteTile(tileIDs[1], []byte("ghi")); err != nil {
+			t.Fatal(err)
+		}
+		data, _ := os.ReadFile(w.tilePath(tileIDs[0]))
+		if string(data) != "abc" {
+			t.Errorf("link mode %v: tile was modified by replacing linked tile", link)
+		}
+
+		existing, err := w.ReadTileIDs()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(existing) != 3 || !existing[*tileIDs[0]] {
+			t.Errorf("link mode %v: %v not expected tiles", link, existing)
+		}
+
+		// reopen and verify that metadata was retained
+		w, err = OpenDirectoryWriter(path, link)
+		if err != nil {
+			t.Fatal(err)
+		}
+		metadata, _ := w.ReadMetadata()
+		if metadata["name"] != "test" || metadata["maxzoom"] != "2" {
+			t.Errorf("link mode %v: metadata %v not expected value", link, metadata)
+		}
+	}
+}
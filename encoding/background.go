@@ -0,0 +1,118 @@
+package encoding
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// BackgroundEncoder fills transparent pixels of images rendered by a Renderer
+// with a solid background color.  If the background is opaque gray and all
+// pixels are gray, tiles are encoded to grayscale PNG.
+type BackgroundEncoder struct {
+	pngEncoder
+	renderer   Renderer
+	background color.NRGBA
+	img        *image.RGBA
+	img16      *image.RGBA64 // used for images with 16-bit channels
+	gray       *image.Gray
+	gray16     *image.Gray16
+	palette    color.Palette
+}
+
+// Create a BackgroundEncoder for images rendered by renderer
+func NewBackgroundEncoder(renderer Renderer, width int, height int, background color.NRGBA) *BackgroundEncoder {
+	bounds := image.Rect(0, 0, width, height)
+	return &BackgroundEncoder{
+		renderer:   renderer,
+		background: background,
+		img:        image.NewRGBA(bounds),
+		img16:      image.NewRGBA64(bounds),
+		gray:       image.NewGray(bounds),
+		gray16:     image.NewGray16(bounds),
+	}
+}
+
+// Render image using renderer, and fill transparent pixels with background
+func (e *BackgroundEncoder) Render(buffer interface{}, mask []uint8) (image.Image, error) {
+	img, err := e.renderer.Render(buffer, mask)
+	if err != nil {
+		return nil, err
+	}
+	if o, ok := img.(interface{ Opaque() bool }); ok && o.Opaque() {
+		return img, nil
+	}
+
+	isGray := e.background.A == 255 && e.background.R == e.background.G && e.background.G == e.background.B
+	background := image.NewUniform(e.background)
+
+	switch typedImg := img.(type) {
+	case *image.Paletted:
+		// only palette colors need to be filled with background
+		e.palette = e.palette[:0]
+		for _, c := range typedImg.Palette {
+			e.palette = append(e.palette, over(color.NRGBAModel.Convert(c).(color.NRGBA), e.background))
+		}
+		return &image.Paletted{Pix: typedImg.Pix, Stride: typedImg.Stride, Rect: typedImg.Rect, Palette: e.palette}, nil
+
+	case *image.NRGBA64, *image.RGBA64, *image.Gray16:
+		draw.Draw(e.img16, e.img16.Bounds(), background, image.Point{}, draw.Src)
+		draw.Draw(e.img16, e.img16.Bounds(), img, img.Bounds().Min, draw.Over)
+		// RGBA64 pixels are 8 bytes of big-endian red, green, blue, alpha
+		pix := e.img16.Pix
+		for i := 0; isGray && i < len(pix); i += 8 {
+			isGray = pix[i] == pix[i+2] && pix[i] == pix[i+4] && pix[i+1] == pix[i+3] && pix[i+1] == pix[i+5]
+		}
+		if !isGray {
+			return e.img16, nil
+		}
+		for i := 0; i < len(e.gray16.Pix); i += 2 {
+			e.gray16.Pix[i] = pix[4*i]
+			e.gray16.Pix[i+1] = pix[4*i+1]
+		}
+		return e.gray16, nil
+
+	default:
+		draw.Draw(e.img, e.img.Bounds(), background, image.Point{}, draw.Src)
+		draw.Draw(e.img, e.img.Bounds(), img, img.Bounds().Min, draw.Over)
+		pix := e.img.Pix
+		for i := 0; isGray && i < len(pix); i += 4 {
+			isGray = pix[i] == pix[i+1] && pix[i] == pix[i+2]
+		}
+		if !isGray {
+			return e.img, nil
+		}
+		for i := range e.gray.Pix {
+			e.gray.Pix[i] = pix[4*i]
+		}
+		return e.gray, nil
+	}
+}
+
+// Encode buffer to PNG
+func (e *BackgroundEncoder) Encode(buffer interface{}, mask []uint8) ([]byte, error) {
+	img, err := e.Render(buffer, mask)
+	if err != nil {
+		return nil, err
+	}
+	return e.encode(img)
+}
+
+// Composite color c over background
+func over(c color.NRGBA, background color.NRGBA) color.NRGBA {
+	if c.A == 255 {
+		return c
+	}
+
+	alpha := float64(c.A) / 255
+	backgroundAlpha := float64(background.A) / 255 * (1 - alpha)
+	outAlpha := alpha + backgroundAlpha
+	if outAlpha == 0 {
+		return color.NRGBA{}
+	}
+	blend := func(v uint8, b uint8) uint8 {
+		return uint8(math.Round((float64(v)*alpha + float64(b)*backgroundAlpha) / outAlpha))
+	}
+	return color.NRGBA{blend(c.R, background.R), blend(c.G, background.G), blend(c.B, background.B), uint8(math.Round(outAlpha * 255))}
+}
@@ -0,0 +1,81 @@
+package encoding
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestBackgroundEncoder(t *testing.T) {
+	mask := []uint8{255, 0, 255}
+
+	// gray background of grayscale image is grayscale
+	encoder := NewBackgroundEncoder(NewGrayscaleEncoder(3, 1, nil), 3, 1, color.NRGBA{128, 128, 128, 255})
+	img, err := encoder.Render([]uint8{10, 20, 30}, mask)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gray, ok := img.(*image.Gray)
+	if !ok {
+		t.Fatalf("rendered image is not Gray: %T", img)
+	}
+	for col, expected := range []uint8{10, 128, 30} {
+		if value := gray.GrayAt(col, 0).Y; value != expected {
+			t.Errorf("pixel %v: %v does not match expected value %v", col, value, expected)
+		}
+	}
+
+	// 16-bit values are retained
+	encoder = NewBackgroundEncoder(NewGrayscale16Encoder(3, 1, nil), 3, 1, color.NRGBA{255, 255, 255, 255})
+	img, err = encoder.Render([]uint16{1000, 2000, 3000}, mask)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gray16, ok := img.(*image.Gray16)
+	if !ok {
+		t.Fatalf("rendered image is not Gray16: %T", img)
+	}
+	for col, expected := range []uint16{1000, 65535, 3000} {
+		if value := gray16.Gray16At(col, 0).Y; value != expected {
+			t.Errorf("16-bit pixel %v: %v does not match expected value %v", col, value, expected)
+		}
+	}
+
+	// colored background
+	background := color.NRGBA{255, 0, 0, 255}
+	encoder = NewBackgroundEncoder(NewGrayscaleEncoder(3, 1, nil), 3, 1, background)
+	img, err = encoder.Render([]uint8{10, 20, 30}, []uint8{255, 0, 51})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []color.NRGBA{{10, 10, 10, 255}, background, {210, 6, 6, 255}}
+	for col, c := range expected {
+		if value := color.NRGBAModel.Convert(img.At(col, 0)); value != c {
+			t.Errorf("pixel %v: %v does not match expected value %v", col, value, c)
+		}
+	}
+
+	// paletted images remain paletted
+	colormap, err := NewColormap("1:#FF0000,2:#0000FF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	colormapEncoder, err := NewColormapEncoder(3, 1, colormap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoder = NewBackgroundEncoder(colormapEncoder, 3, 1, color.NRGBA{0, 255, 0, 255})
+	img, err = encoder.Render([]uint8{1, 0, 2}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := img.(*image.Paletted); !ok {
+		t.Errorf("rendered image is not Paletted: %T", img)
+	}
+	expected = []color.NRGBA{{255, 0, 0, 255}, {0, 255, 0, 255}, {0, 0, 255, 255}}
+	for col, c := range expected {
+		if value := color.NRGBAModel.Convert(img.At(col, 0)); value != c {
+			t.Errorf("paletted pixel %v: %v does not match expected value %v", col, value, c)
+		}
+	}
+}
@@ -1,69 +1,232 @@
 package encoding
 
 import (
-	"bytes"
 	"fmt"
 	"image"
 	"image/color"
-	"image/png"
+	"math"
+	"sort"
 	"strconv"
 	"strings"
+
+	"github.com/brendan-ward/rastertiler/array"
 )
 
+// ColormapEntry is the color and optional label of an inclusive range of
+// values of a categorical colormap; Min and Max are equal for a single value
+type ColormapEntry struct {
+	Min   int64
+	Max   int64
+	Color color.NRGBA
+	Label string
+}
+
+// colormapRange is the palette index of an inclusive range of values
+type colormapRange struct {
+	min   int64
+	max   int64
+	index int
+}
+
+// Colormap is either a categorical colormap of integer values or ranges of
+// values to colors, or a continuous Gradient
 type Colormap struct {
-	values  map[uint8]uint8 // map of value to index in palette
-	palette color.Palette
+	ranges   []colormapRange // sorted by value; ranges do not overlap
+	palette  color.Palette   // last color is transparent
+	entries  []ColormapEntry // only set for categorical colormaps
+	gradient *Gradient       // only set for gradient colormaps
 }
 
 // Returns palette index of value
 // any values not in original colormap are set to transparent
-func (c *Colormap) GetIndex(value uint8) uint8 {
-	if index, ok := c.values[value]; ok {
+func (c *Colormap) GetIndex(value int64) int {
+	if index, ok := c.find(value); ok {
 		return index
 	}
-	return uint8(len(c.palette) - 1)
+	return len(c.palette) - 1
+}
+
+// Find the palette index of value using a binary search of ranges
+func (c *Colormap) find(value int64) (int, bool) {
+	i := sort.Search(len(c.ranges), func(i int) bool { return c.ranges[i].max >= value })
+	if i < len(c.ranges) && c.ranges[i].min <= value {
+		return c.ranges[i].index, true
+	}
+	return 0, false
 }
 
 func (c *Colormap) Palette() color.Palette {
 	return c.palette
 }
 
+// Returns gradient of colormap, or nil if colormap is categorical
+func (c *Colormap) Gradient() *Gradient {
+	return c.gradient
+}
+
+// Returns entries of categorical colormap in original order
+func (c *Colormap) Entries() []ColormapEntry {
+	return c.entries
+}
+
+// Returns true if any entry of colormap has a label
+func (c *Colormap) HasLabels() bool {
+	for _, entry := range c.entries {
+		if entry.Label != "" {
+			return true
+		}
+	}
+	return false
+}
+
 // Create new colormap by parsing colormap string, which is a comma-delimited
-// set of <value>:<hex> entries, e.g., "1:#AABBCC,2:#DDEEFF"
+// set of <value>:<color> or <min>-<max>:<color> entries, where ranges include
+// min and max, e.g., "1:#AABBCC,2:#DDEEFF80,10-19:red", or a gradient (see
+// NewGradient).  See ParseColor for supported colors.
 func NewColormap(colormap string) (*Colormap, error) {
-	entries := strings.Split(strings.ReplaceAll(colormap, " ", ""), ",")
-
-	palette := make([]color.Color, len(entries)+1)
-	values := make(map[uint8]uint8, len(entries))
-	for i, entry := range entries {
-		parts := strings.Split(entry, ":")
-		value, err := strconv.ParseUint(parts[0], 10, 8)
+	if IsGradient(colormap) {
+		gradient, err := NewGradient(colormap)
 		if err != nil {
 			return nil, err
 		}
-		values[uint8(value)] = uint8(i)
+		return &Colormap{gradient: gradient}, nil
+	}
+
+	parts := strings.Split(strings.ReplaceAll(colormap, " ", ""), ",")
+
+	entries := make([]ColormapEntry, 0, len(parts))
+	for _, part := range parts {
+		valueColor := strings.Split(part, ":")
+		if len(valueColor) != 2 {
+			return nil, fmt.Errorf("invalid colormap entry: %v", part)
+		}
+
+		// the separator of a range follows the first character, as the
+		// minimum may be negative
+		value := valueColor[0]
+		bounds := []string{value, value}
+		if len(value) > 1 {
+			if i := strings.Index(value[1:], "-"); i >= 0 {
+				bounds = []string{value[:i+1], value[i+2:]}
+			}
+		}
+		var values [2]int64
+		for i, bound := range bounds {
+			v, err := strconv.ParseInt(bound, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid colormap value: %v", value)
+			}
+			values[i] = v
+		}
 
-		color, err := parseHex(parts[1])
+		color, err := ParseColor(valueColor[1])
 		if err != nil {
 			return nil, err
 		}
+		entries = append(entries, ColormapEntry{Min: values[0], Max: values[1], Color: color})
+	}
+
+	return NewColormapFromEntries(entries)
+}
+
+// Create new categorical colormap from entries, e.g., from the color table
+// of a dataset.  Ranges of entries must not overlap.
+func NewColormapFromEntries(entries []ColormapEntry) (*Colormap, error) {
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("colormap must have at least one entry")
+	}
+
+	// values with the same color share a palette index
+	palette := make([]color.Color, 0, len(entries)+1)
+	paletteIndexes := make(map[color.NRGBA]int)
+	ranges := make([]colormapRange, len(entries))
+	for i, entry := range entries {
+		if entry.Min > entry.Max {
+			return nil, fmt.Errorf("colormap range minimum must not be greater than maximum: %v-%v", entry.Min, entry.Max)
+		}
+		index, ok := paletteIndexes[entry.Color]
+		if !ok {
+			index = len(palette)
+			paletteIndexes[entry.Color] = index
+			palette = append(palette, entry.Color)
+		}
+		ranges[i] = colormapRange{entry.Min, entry.Max, index}
+	}
+	palette = append(palette, color.Transparent)
 
-		palette[i] = color
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].min < ranges[j].min })
+	for i := 1; i < len(ranges); i++ {
+		if ranges[i].min <= ranges[i-1].max {
+			return nil, fmt.Errorf("colormap has duplicate or overlapping values: %v", ranges[i].min)
+		}
 	}
-	palette[len(entries)] = color.Transparent
 
 	return &Colormap{
-		values:  values,
+		ranges:  ranges,
 		palette: palette,
+		entries: entries,
 	}, nil
 }
 
+// Create new categorical colormap from a color table, where colors and
+// optional labels are indexed by value
+func NewColormapFromColorTable(colors []color.NRGBA, labels []string) (*Colormap, error) {
+	entries := make([]ColormapEntry, len(colors))
+	for value, c := range colors {
+		entries[value] = ColormapEntry{Min: int64(value), Max: int64(value), Color: c}
+		if value < len(labels) {
+			entries[value].Label = labels[value]
+		}
+	}
+	return NewColormapFromEntries(entries)
+}
+
+// ValueCount is the number of pixels with a value
+type ValueCount struct {
+	Value int64
+	Count uint64
+}
+
+// Returns values with nonzero counts that are not in colormap, in ascending
+// order of value.  Counts are the number of pixels of each value, indexed by
+// value.
+func (c *Colormap) Missing(counts []uint64) []ValueCount {
+	missing := make([]ValueCount, 0)
+	for value, count := range counts {
+		if count == 0 {
+			continue
+		}
+		if _, ok := c.find(int64(value)); !ok {
+			missing = append(missing, ValueCount{int64(value), count})
+		}
+	}
+	return missing
+}
+
+// Format color as #RRGGBB hex, or #RRGGBBAA if not opaque
+func FormatHex(c color.NRGBA) string {
+	if c.A == 255 {
+		return fmt.Sprintf("#%02X%02X%02X", c.R, c.G, c.B)
+	}
+	return fmt.Sprintf("#%02X%02X%02X%02X", c.R, c.G, c.B, c.A)
+}
+
+// Parse color, which is either hex (#RGB, #RGBA, #RRGGBB, or #RRGGBBAA), or a
+// CSS color name (e.g., "steelblue" or "transparent")
+func ParseColor(value string) (color.NRGBA, error) {
+	value = strings.TrimSpace(value)
+	if c, ok := cssColors[strings.ToLower(value)]; ok {
+		return c, nil
+	}
+	return parseHex(value)
+}
+
 // from: https://stackoverflow.com/a/54200713/2740575
 func parseHex(hex string) (c color.NRGBA, err error) {
 	c.A = 0xff
 
-	if hex[0] != '#' {
-		return c, fmt.Errorf("Invalid hex color format")
+	if len(hex) == 0 || hex[0] != '#' {
+		return c, fmt.Errorf("Invalid color format: %q", hex)
 	}
 
 	hexToByte := func(b byte) byte {
@@ -75,58 +238,193 @@ func parseHex(hex string) (c color.NRGBA, err error) {
 		case b >= 'A' && b <= 'F':
 			return b - 'A' + 10
 		}
-		err = fmt.Errorf("Invalid hex color format")
+		err = fmt.Errorf("Invalid hex color format: %q", hex)
 		return 0
 	}
 
 	switch len(hex) {
+	case 9:
+		c.A = hexToByte(hex[7])<<4 + hexToByte(hex[8])
+		fallthrough
 	case 7:
 		c.R = hexToByte(hex[1])<<4 + hexToByte(hex[2])
 		c.G = hexToByte(hex[3])<<4 + hexToByte(hex[4])
 		c.B = hexToByte(hex[5])<<4 + hexToByte(hex[6])
+	case 5:
+		c.A = hexToByte(hex[4]) * 17
+		fallthrough
 	case 4:
 		c.R = hexToByte(hex[1]) * 17
 		c.G = hexToByte(hex[2]) * 17
 		c.B = hexToByte(hex[3]) * 17
 	default:
-		err = fmt.Errorf("Invalid hex color format")
+		err = fmt.Errorf("Invalid hex color format: %q", hex)
 	}
 	return c, err
 }
 
+// ColormapEncoder encodes uint8 values to 8-bit paletted PNG using a
+// categorical colormap
 type ColormapEncoder struct {
-	colormap  *Colormap
-	img       *image.Paletted
-	pngBuffer bytes.Buffer
-	width     int
-	height    int
+	pngEncoder
+	colormap *Colormap
+	indexes  [math.MaxUint8 + 1]uint8 // palette index of each value
+	img      *image.Paletted
+	width    int
+	height   int
 }
 
-func NewColormapEncoder(width int, height int, colormap *Colormap) *ColormapEncoder {
-	return &ColormapEncoder{
+// Create a ColormapEncoder; colormap must have no more than 255 colors
+func NewColormapEncoder(width int, height int, colormap *Colormap) (*ColormapEncoder, error) {
+	if len(colormap.Palette()) > 256 {
+		return nil, fmt.Errorf("colormap for paletted PNG must have no more than 255 colors")
+	}
+
+	e := &ColormapEncoder{
 		colormap: colormap,
 		img:      image.NewPaletted(image.Rect(0, 0, width, height), colormap.Palette()),
 		width:    width,
 		height:   height,
 	}
+	for i := range e.indexes {
+		e.indexes[i] = uint8(colormap.GetIndex(int64(i)))
+	}
+	return e, nil
 }
 
-func (e *ColormapEncoder) Encode(buffer interface{}) ([]byte, error) {
+// Render uint8 values to paletted image.  Pixels that are fully transparent
+// in mask are transparent.
+func (e *ColormapEncoder) Render(buffer interface{}, mask []uint8) (image.Image, error) {
+	transparent := uint8(len(e.colormap.Palette()) - 1)
+
 	switch typedBuffer := buffer.(type) {
 	case []uint8:
-		var value uint8
-		for row := 0; row < e.height; row++ {
-			for col := 0; col < e.width; col++ {
-				value = typedBuffer[row*e.width+col]
-				e.img.SetColorIndex(col, row, e.colormap.GetIndex(value))
+		for i := 0; i < e.width*e.height; i++ {
+			if mask != nil && mask[i] == 0 {
+				e.img.Pix[i] = transparent
+				continue
 			}
+			e.img.Pix[i] = e.indexes[typedBuffer[i]]
 		}
+	default:
+		panic("Other dtypes not supported for ColormapEncoder::Encode()")
 	}
 
-	e.pngBuffer.Reset()
-	err := png.Encode(&e.pngBuffer, e.img)
+	return e.img, nil
+}
+
+// Encode buffer to PNG
+func (e *ColormapEncoder) Encode(buffer interface{}, mask []uint8) ([]byte, error) {
+	img, err := e.Render(buffer, mask)
+	if err != nil {
+		return nil, err
+	}
+	return e.encode(img)
+}
+
+// Colormap16Encoder encodes values of integer dtypes other than uint8, or
+// uint8 values if the colormap has too many colors for ColormapEncoder, using
+// a categorical colormap.  Values are encoded to 8-bit paletted PNG if the
+// colormap has no more than 255 colors, otherwise they are encoded to RGBA
+// PNG.
+type Colormap16Encoder struct {
+	pngEncoder
+	colormap *Colormap
+	indexes  []int         // palette index of each value for 8-bit and 16-bit data
+	offset   int64         // offset of values into indexes
+	colors   []color.NRGBA // colors of palette
+	img      image.Image
+	width    int
+	height   int
+}
+
+// Create a Colormap16Encoder for dtype.  The palette index of all possible
+// values of 8-bit and 16-bit dtypes is precomputed; values of 32-bit dtypes
+// are found using a binary search of the ranges of the colormap.
+func NewColormap16Encoder(width int, height int, colormap *Colormap, dtype string) *Colormap16Encoder {
+	e := &Colormap16Encoder{
+		colormap: colormap,
+		width:    width,
+		height:   height,
+	}
+
+	var size int
+	switch dtype {
+	case "int8":
+		size = math.MaxUint8 + 1
+		e.offset = -math.MinInt8
+	case "uint8":
+		size = math.MaxUint8 + 1
+	case "int16":
+		size = math.MaxUint16 + 1
+		e.offset = -math.MinInt16
+	case "uint16":
+		size = math.MaxUint16 + 1
+	}
+	if size > 0 {
+		e.indexes = make([]int, size)
+		for i := range e.indexes {
+			e.indexes[i] = colormap.GetIndex(int64(i) - e.offset)
+		}
+	}
+
+	palette := colormap.Palette()
+	e.colors = make([]color.NRGBA, len(palette))
+	for i, c := range palette {
+		e.colors[i] = color.NRGBAModel.Convert(c).(color.NRGBA)
+	}
+
+	if len(palette) <= 256 {
+		e.img = image.NewPaletted(image.Rect(0, 0, width, height), palette)
+	} else {
+		e.img = image.NewNRGBA(image.Rect(0, 0, width, height))
+	}
+
+	return e
+}
+
+// Render values to paletted or RGBA image.  Pixels that are fully
+// transparent in mask are transparent; alpha is scaled by mask for RGBA.
+func (e *Colormap16Encoder) Render(buffer interface{}, mask []uint8) (image.Image, error) {
+	transparent := len(e.colors) - 1
+	get, _ := array.Accessors(buffer)
+
+	index := func(i int) int {
+		value := int64(get(i))
+		if e.indexes != nil {
+			return e.indexes[value+e.offset]
+		}
+		return e.colormap.GetIndex(value)
+	}
+
+	switch img := e.img.(type) {
+	case *image.Paletted:
+		for i := 0; i < e.width*e.height; i++ {
+			if mask != nil && mask[i] == 0 {
+				img.Pix[i] = uint8(transparent)
+				continue
+			}
+			img.Pix[i] = uint8(index(i))
+		}
+	case *image.NRGBA:
+		var c color.NRGBA
+		for i := 0; i < e.width*e.height; i++ {
+			c = e.colors[index(i)]
+			img.Pix[4*i] = c.R
+			img.Pix[4*i+1] = c.G
+			img.Pix[4*i+2] = c.B
+			img.Pix[4*i+3] = maskAlpha(c.A, mask, i)
+		}
+	}
+
+	return e.img, nil
+}
+
+// Encode buffer to PNG
+func (e *Colormap16Encoder) Encode(buffer interface{}, mask []uint8) ([]byte, error) {
+	img, err := e.Render(buffer, mask)
 	if err != nil {
 		return nil, err
 	}
-	return e.pngBuffer.Bytes(), nil
+	return e.encode(img)
 }
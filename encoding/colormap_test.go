@@ -1,7 +1,12 @@
 package encoding
 
 import (
+	"bytes"
+	"fmt"
+	"image"
 	"image/color"
+	"image/png"
+	"strings"
 	"testing"
 )
 
@@ -16,7 +21,7 @@ func TestNewColormap(t *testing.T) {
 	expectedPalette[2] = color.NRGBA{255, 0, 0, 255}
 	expectedPalette[3] = color.Transparent
 
-	expectedIndexes := map[uint8]uint8{
+	expectedIndexes := map[int64]int{
 		0: 3,
 		1: 0,
 		2: 3,
@@ -42,3 +47,187 @@ func TestNewColormap(t *testing.T) {
 		}
 	}
 }
+
+func TestNewColormap16(t *testing.T) {
+	colormap, err := NewColormap("1000:#000000,65535:#FFFFFF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index := colormap.GetIndex(1000); index != 0 {
+		t.Errorf("value 1000: index %v does not match expected index 0", index)
+	}
+	if index := colormap.GetIndex(65535); index != 1 {
+		t.Errorf("value 65535: index %v does not match expected index 1", index)
+	}
+
+	if _, err = NewColormap("9223372036854775808:#000000"); err == nil {
+		t.Errorf("value out of range for int64 did not raise error")
+	}
+}
+
+func TestColormap16Encoder(t *testing.T) {
+	// more than 255 colors are encoded to RGBA
+	entries := make([]string, 300)
+	for i := range entries {
+		entries[i] = fmt.Sprintf("%v:#%02x0000", 1000+i, i%256)
+	}
+	colormap, err := NewColormap(strings.Join(entries, ","))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encoder := NewColormap16Encoder(3, 1, colormap, "uint16")
+	data, err := encoder.Encode([]uint16{1000, 1299, 0}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []color.NRGBA{{0, 0, 0, 255}, {43, 0, 0, 255}, {0, 0, 0, 0}}
+	for col, c := range expected {
+		if value := color.NRGBAModel.Convert(img.At(col, 0)); value != c {
+			t.Errorf("pixel %v: %v does not match expected value %v", col, value, c)
+		}
+	}
+
+	// fewer values are encoded to paletted PNG
+	colormap, err = NewColormap("1000:#FF0000,2000:#00FF00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err = NewColormap16Encoder(2, 1, colormap, "uint16").Render([]uint16{2000, 1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paletted, ok := img.(*image.Paletted)
+	if !ok {
+		t.Fatalf("rendered image is not paletted: %T", img)
+	}
+	if paletted.Pix[0] != 1 || paletted.Pix[1] != 2 {
+		t.Errorf("palette indexes %v do not match expected indexes [1 2]", paletted.Pix)
+	}
+}
+
+func TestColormapEncoderMask(t *testing.T) {
+	colormap, err := NewColormap("1:#FF0000,2:#00FF00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoder, err := NewColormapEncoder(2, 1, colormap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := encoder.Render([]uint8{1, 2}, []uint8{255, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []color.NRGBA{{255, 0, 0, 255}, {}}
+	for col, c := range expected {
+		if value := color.NRGBAModel.Convert(img.At(col, 0)); value != c {
+			t.Errorf("pixel %v: %v does not match expected value %v", col, value, c)
+		}
+	}
+}
+
+func TestColormapRanges(t *testing.T) {
+	colormap, err := NewColormap("-100--10:#FF000080,0:red,10-19:#0f08,1000000-2000000:steelblue")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[int64]color.NRGBA{
+		-101:    {},
+		-100:    {255, 0, 0, 128},
+		-10:     {255, 0, 0, 128},
+		-9:      {},
+		0:       {255, 0, 0, 255},
+		9:       {},
+		10:      {0, 255, 0, 136},
+		19:      {0, 255, 0, 136},
+		20:      {},
+		1500000: {70, 130, 180, 255},
+		2000001: {},
+	}
+	palette := colormap.Palette()
+	for value, c := range expected {
+		if index := colormap.GetIndex(value); color.NRGBAModel.Convert(palette[index]) != c {
+			t.Errorf("value %v: color %v does not match expected color %v", value, palette[index], c)
+		}
+	}
+
+	for _, invalid := range []string{"10-5:red", "1-10:red,5:blue", "1:notacolor", "1-:red", "1:#12345"} {
+		if _, err := NewColormap(invalid); err == nil {
+			t.Errorf("invalid colormap %q did not raise error", invalid)
+		}
+	}
+}
+
+func TestColormap16EncoderDtypes(t *testing.T) {
+	colormap, err := NewColormap("-5--1:#FF0000,0:#00FF00,100000-200000:#0000FF")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		dtype  string
+		values interface{}
+	}{
+		{"int8", []int8{-3, 0, 5}},
+		{"int16", []int16{-3, 0, 5}},
+		{"int32", []int32{-3, 0, 150000}},
+		{"uint32", []uint32{4294967295, 0, 150000}},
+	}
+	expected := [][]color.NRGBA{
+		{{255, 0, 0, 255}, {0, 255, 0, 255}, {}},
+		{{255, 0, 0, 255}, {0, 255, 0, 255}, {}},
+		{{255, 0, 0, 255}, {0, 255, 0, 255}, {0, 0, 255, 255}},
+		{{}, {0, 255, 0, 255}, {0, 0, 255, 255}},
+	}
+
+	for i, test := range tests {
+		encoder, err := NewEncoder(test.dtype, 1, 3, 1, colormap, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := encoder.Render(test.values, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for col, c := range expected[i] {
+			if value := color.NRGBAModel.Convert(img.At(col, 0)); value != c {
+				t.Errorf("%v pixel %v: %v does not match expected value %v", test.dtype, col, value, c)
+			}
+		}
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	tests := map[string]color.NRGBA{
+		"#abc":        {0xaa, 0xbb, 0xcc, 255},
+		"#abcd":       {0xaa, 0xbb, 0xcc, 0xdd},
+		"#AABBCC":     {0xaa, 0xbb, 0xcc, 255},
+		"#AABBCC80":   {0xaa, 0xbb, 0xcc, 0x80},
+		"SteelBlue":   {70, 130, 180, 255},
+		"transparent": {},
+	}
+	for value, expected := range tests {
+		c, err := ParseColor(value)
+		if err != nil {
+			t.Errorf("%v: %v", value, err)
+			continue
+		}
+		if c != expected {
+			t.Errorf("%v: %v does not match expected color %v", value, c, expected)
+		}
+	}
+
+	for _, invalid := range []string{"", "#", "#12", "#1234567", "#GGGGGG", "notacolor"} {
+		if _, err := ParseColor(invalid); err == nil {
+			t.Errorf("invalid color %q did not raise error", invalid)
+		}
+	}
+}
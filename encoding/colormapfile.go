@@ -0,0 +1,379 @@
+package encoding
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// colormapRow is an entry of a colormap file, which is either a categorical
+// value, range, or upper bound of a class, or a gradient stop.  Min and max
+// are equal unless the entry is a range.
+type colormapRow struct {
+	min   float64
+	max   float64
+	color color.NRGBA
+	label string
+}
+
+// colormapKind determines how rows of a colormap file are interpreted
+type colormapKind int
+
+const (
+	// rows are values or ranges of values
+	exactColormap colormapKind = iota
+	// rows are inclusive upper bounds of classes that start after the upper
+	// bound of the previous row, e.g., QGIS DISCRETE interpolation
+	inclusiveClassColormap
+	// rows are exclusive upper bounds of classes that start at the upper
+	// bound of the previous row, e.g., SLD intervals
+	exclusiveClassColormap
+	// rows are gradient stops
+	gradientColormap
+)
+
+// Read colormap from a file, according to its extension:
+//   - .json: JSON object with "type" ("values" or "gradient") and "entries",
+//     which are objects with "value" or "min" and "max", "color" (see
+//     ParseColor), and optional "label"
+//   - .sld or .xml: SLD ColorMap, with type "values", "intervals", or "ramp"
+//   - any other extension: GDAL or QGIS color map text export, with an entry
+//     of value, red, green, blue, optional alpha, and optional label per line
+func ReadColormap(filename string) (*Colormap, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var colormap *Colormap
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		colormap, err = parseJSONColormap(data)
+	case ".sld", ".xml":
+		colormap, err = parseSLDColormap(data)
+	default:
+		colormap, err = parseTextColormap(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid colormap file %v: %v", filename, err)
+	}
+	return colormap, nil
+}
+
+// Create categorical colormap or gradient from rows of a colormap file
+func newColormapFromRows(rows []colormapRow, kind colormapKind) (*Colormap, error) {
+	if kind == gradientColormap {
+		g := &Gradient{space: RGB, clamp: true}
+		for _, row := range rows {
+			if err := g.addStop(row.min, row.color); err != nil {
+				return nil, err
+			}
+		}
+		if err := g.validate(); err != nil {
+			return nil, err
+		}
+		return &Colormap{gradient: g}, nil
+	}
+
+	entries := make([]ColormapEntry, 0, len(rows))
+	lower := math.Inf(-1) // lower bound of next class
+	for i, row := range rows {
+		minValue, maxValue := row.min, row.max
+		if kind != exactColormap {
+			if i > 0 && row.max <= rows[i-1].max {
+				return nil, fmt.Errorf("class upper bounds must be in ascending order: %v", row.max)
+			}
+			// classes are converted to inclusive ranges of integers
+			minValue, maxValue = lower, math.Floor(row.max)
+			if kind == exclusiveClassColormap {
+				maxValue = math.Ceil(row.max) - 1
+			}
+			lower = maxValue + 1
+			if minValue > maxValue {
+				// class does not include any integers
+				continue
+			}
+		}
+
+		min, err := toInt64(minValue)
+		if err != nil {
+			return nil, err
+		}
+		max, err := toInt64(maxValue)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, ColormapEntry{Min: min, Max: max, Color: row.color, Label: row.label})
+	}
+	return NewColormapFromEntries(entries)
+}
+
+// Convert integer value to int64, clamping infinite values
+func toInt64(value float64) (int64, error) {
+	switch {
+	case value <= math.MinInt64:
+		return math.MinInt64, nil
+	case value >= math.MaxInt64:
+		return math.MaxInt64, nil
+	case value != math.Trunc(value):
+		return 0, fmt.Errorf("categorical colormap values must be integers: %v", value)
+	}
+	return int64(value), nil
+}
+
+// Parse GDAL color map text (as used by gdaldem color-relief) or QGIS color
+// map export.  Lines are comma, tab, or space delimited; lines starting with
+// '#' are comments, and "nv" (nodata) entries are ignored because nodata is
+// transparent.  Entries are categorical unless the QGIS header specifies
+// "INTERPOLATION:DISCRETE", in which case they are inclusive upper bounds of
+// classes, or "INTERPOLATION:INTERPOLATED", in which case they are gradient
+// stops.
+func parseTextColormap(data []byte) (*Colormap, error) {
+	rows := make([]colormapRow, 0)
+	kind := exactColormap
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if strings.HasPrefix(strings.ToUpper(line), "INTERPOLATION:") {
+			switch interpolation := strings.ToUpper(strings.TrimSpace(line[14:])); interpolation {
+			case "EXACT":
+				kind = exactColormap
+			case "DISCRETE":
+				kind = inclusiveClassColormap
+			case "INTERPOLATED":
+				kind = gradientColormap
+			default:
+				return nil, fmt.Errorf("interpolation not supported: %v", interpolation)
+			}
+			continue
+		}
+
+		// QGIS uses commas, and labels may contain spaces
+		var fields []string
+		if strings.Contains(line, ",") {
+			fields = strings.SplitN(line, ",", 6)
+			for i := range fields {
+				fields[i] = strings.TrimSpace(fields[i])
+			}
+		} else {
+			fields = strings.Fields(line)
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("invalid colormap entry: %v", line)
+		}
+		if strings.ToLower(fields[0]) == "nv" {
+			continue
+		}
+		if strings.HasSuffix(fields[0], "%") {
+			return nil, fmt.Errorf("percentage values not supported: %v", fields[0])
+		}
+
+		value, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid colormap value: %v", fields[0])
+		}
+
+		// color is either a color name or hex, or red, green, blue, and
+		// optional alpha; any remaining fields are the label
+		var c color.NRGBA
+		labelStart := 2
+		if _, err := strconv.ParseUint(fields[1], 10, 8); err != nil {
+			if c, err = ParseColor(fields[1]); err != nil {
+				return nil, fmt.Errorf("invalid color in colormap entry: %v", line)
+			}
+		} else {
+			if len(fields) < 4 {
+				return nil, fmt.Errorf("invalid colormap entry: %v", line)
+			}
+			rgba := [4]uint8{0, 0, 0, 255}
+			for i := 0; i < 3; i++ {
+				channel, err := strconv.ParseUint(fields[i+1], 10, 8)
+				if err != nil {
+					return nil, fmt.Errorf("invalid color in colormap entry: %v", line)
+				}
+				rgba[i] = uint8(channel)
+			}
+			labelStart = 4
+			if len(fields) > 4 {
+				if alpha, err := strconv.ParseUint(fields[4], 10, 8); err == nil {
+					rgba[3] = uint8(alpha)
+					labelStart = 5
+				}
+			}
+			c = color.NRGBA{rgba[0], rgba[1], rgba[2], rgba[3]}
+		}
+		label := ""
+		if len(fields) > labelStart {
+			label = strings.Join(fields[labelStart:], " ")
+		}
+
+		rows = append(rows, colormapRow{min: value, max: value, color: c, label: label})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return newColormapFromRows(rows, kind)
+}
+
+// Parse ColorMap of SLD RasterSymbolizer.  ColorMap type "values" is
+// categorical, "intervals" are classes from the quantity of the previous
+// entry up to but excluding the quantity of each entry, and "ramp" (the
+// default) is a gradient.
+func parseSLDColormap(data []byte) (*Colormap, error) {
+	type colorMapEntry struct {
+		Color    string `xml:"color,attr"`
+		Quantity string `xml:"quantity,attr"`
+		Opacity  string `xml:"opacity,attr"`
+		Label    string `xml:"label,attr"`
+	}
+
+	rows := make([]colormapRow, 0)
+	colormapType := "ramp"
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "ColorMap":
+			for _, attr := range start.Attr {
+				if attr.Name.Local == "type" {
+					colormapType = attr.Value
+				}
+			}
+		case "ColorMapEntry":
+			var entry colorMapEntry
+			if err := decoder.DecodeElement(&entry, &start); err != nil {
+				return nil, err
+			}
+			value, err := strconv.ParseFloat(entry.Quantity, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ColorMapEntry quantity: %q", entry.Quantity)
+			}
+			c, err := ParseColor(entry.Color)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ColorMapEntry color: %q", entry.Color)
+			}
+			if entry.Opacity != "" {
+				opacity, err := strconv.ParseFloat(entry.Opacity, 64)
+				if err != nil || opacity < 0 || opacity > 1 {
+					return nil, fmt.Errorf("invalid ColorMapEntry opacity: %q", entry.Opacity)
+				}
+				c.A = uint8(math.Round(opacity * 255))
+			}
+			rows = append(rows, colormapRow{min: value, max: value, color: c, label: entry.Label})
+		}
+	}
+
+	switch colormapType {
+	case "values":
+		return newColormapFromRows(rows, exactColormap)
+	case "intervals":
+		return newColormapFromRows(rows, exclusiveClassColormap)
+	case "ramp":
+		return newColormapFromRows(rows, gradientColormap)
+	default:
+		return nil, fmt.Errorf("ColorMap type not supported: %v", colormapType)
+	}
+}
+
+// Parse JSON colormap, e.g.,
+// {"type": "values", "entries": [{"value": 1, "color": "#AABBCC", "label": "Water"}]}
+func parseJSONColormap(data []byte) (*Colormap, error) {
+	var colormap struct {
+		Type    string `json:"type"`
+		Entries []struct {
+			Value *float64 `json:"value"`
+			Min   *float64 `json:"min"`
+			Max   *float64 `json:"max"`
+			Color string   `json:"color"`
+			Label string   `json:"label"`
+		} `json:"entries"`
+	}
+	if err := json.Unmarshal(data, &colormap); err != nil {
+		return nil, err
+	}
+
+	rows := make([]colormapRow, len(colormap.Entries))
+	for i, entry := range colormap.Entries {
+		switch {
+		case entry.Value != nil:
+			rows[i].min, rows[i].max = *entry.Value, *entry.Value
+		case entry.Min != nil && entry.Max != nil:
+			rows[i].min, rows[i].max = *entry.Min, *entry.Max
+		default:
+			return nil, fmt.Errorf("colormap entry %v must have value or min and max", i)
+		}
+		c, err := ParseColor(entry.Color)
+		if err != nil {
+			return nil, fmt.Errorf("invalid color of colormap entry %v: %q", i, entry.Color)
+		}
+		rows[i].color = c
+		rows[i].label = entry.Label
+	}
+
+	switch colormap.Type {
+	case "", "values":
+		return newColormapFromRows(rows, exactColormap)
+	case "gradient":
+		return newColormapFromRows(rows, gradientColormap)
+	default:
+		return nil, fmt.Errorf("colormap type must be one of values, gradient: %v", colormap.Type)
+	}
+}
+
+// Encode entries of categorical colormap to JSON in the same format read by
+// ReadColormap
+func (c *Colormap) MarshalJSON() ([]byte, error) {
+	type jsonEntry struct {
+		Value *int64 `json:"value,omitempty"`
+		Min   *int64 `json:"min,omitempty"`
+		Max   *int64 `json:"max,omitempty"`
+		Color string `json:"color"`
+		Label string `json:"label,omitempty"`
+	}
+
+	if c.gradient != nil {
+		return nil, fmt.Errorf("JSON encoding of gradients not supported")
+	}
+
+	entries := make([]jsonEntry, len(c.entries))
+	for i := range c.entries {
+		entry := &c.entries[i]
+		entries[i] = jsonEntry{Color: FormatHex(entry.Color), Label: entry.Label}
+		if entry.Min == entry.Max {
+			entries[i].Value = &entry.Min
+		} else {
+			entries[i].Min = &entry.Min
+			entries[i].Max = &entry.Max
+		}
+	}
+	return json.Marshal(struct {
+		Type    string      `json:"type"`
+		Entries []jsonEntry `json:"entries"`
+	}{"values", entries})
+}
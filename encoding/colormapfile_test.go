@@ -0,0 +1,256 @@
+package encoding
+
+import (
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeColormapFile(t *testing.T, name string, content string) string {
+	filename := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func checkEntries(t *testing.T, name string, colormap *Colormap, expected []ColormapEntry) {
+	entries := colormap.Entries()
+	if len(entries) != len(expected) {
+		t.Fatalf("%v: %v entries do not match expected %v entries", name, len(entries), len(expected))
+	}
+	for i, entry := range entries {
+		if entry != expected[i] {
+			t.Errorf("%v: entry %v does not match expected entry %v", name, entry, expected[i])
+		}
+	}
+}
+
+func TestReadColormap(t *testing.T) {
+	expected := []ColormapEntry{
+		{1, 1, color.NRGBA{255, 0, 0, 255}, "Open water"},
+		{2, 2, color.NRGBA{0, 255, 0, 128}, "Forest"},
+		{5, 5, color.NRGBA{0, 0, 255, 255}, ""},
+	}
+
+	tests := map[string]string{
+		"gdal.txt": `# GDAL color map
+1 255 0 0 Open water
+2	0	255	0	128	Forest
+5 blue
+nv 0 0 0 0
+`,
+		"qgis.txt": `# QGIS Generated Color Map Export File
+INTERPOLATION:EXACT
+1,255,0,0,255,Open water
+2,0,255,0,128,Forest
+5,0,0,255,255,
+`,
+		"colormap.sld": `<?xml version="1.0" encoding="UTF-8"?>
+<StyledLayerDescriptor xmlns="http://www.opengis.net/sld" xmlns:sld="http://www.opengis.net/sld">
+  <sld:RasterSymbolizer>
+    <sld:ColorMap type="values">
+      <sld:ColorMapEntry color="#ff0000" quantity="1" label="Open water"/>
+      <sld:ColorMapEntry color="#00ff00" quantity="2" opacity="0.5" label="Forest"/>
+      <sld:ColorMapEntry color="#0000ff" quantity="5"/>
+    </sld:ColorMap>
+  </sld:RasterSymbolizer>
+</StyledLayerDescriptor>
+`,
+		"colormap.json": `{"type": "values", "entries": [
+	{"value": 1, "color": "red", "label": "Open water"},
+	{"value": 2, "color": "#00FF0080", "label": "Forest"},
+	{"value": 5, "color": "#0000FF"}
+]}`,
+	}
+
+	for name, content := range tests {
+		colormap, err := ReadColormap(writeColormapFile(t, name, content))
+		if err != nil {
+			t.Errorf("%v: %v", name, err)
+			continue
+		}
+		checkEntries(t, name, colormap, expected)
+		if !colormap.HasLabels() {
+			t.Errorf("%v: colormap does not have labels", name)
+		}
+	}
+}
+
+func TestReadColormapJSON(t *testing.T) {
+	colormap, err := ReadColormap(writeColormapFile(t, "colormap.json", `{"entries": [
+	{"value": 1, "color": "#FF0000", "label": "Open water"},
+	{"value": 5, "color": "#0000FF"}
+]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkEntries(t, "json", colormap, []ColormapEntry{
+		{1, 1, color.NRGBA{255, 0, 0, 255}, "Open water"},
+		{5, 5, color.NRGBA{0, 0, 255, 255}, ""},
+	})
+
+	// colormap encoded to JSON can be read back
+	data, err := colormap.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	roundtrip, err := ReadColormap(writeColormapFile(t, "roundtrip.json", string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkEntries(t, "roundtrip", roundtrip, colormap.Entries())
+}
+
+func TestReadColormapClasses(t *testing.T) {
+	expected := []ColormapEntry{
+		{math.MinInt64, 10, color.NRGBA{255, 0, 0, 255}, "low"},
+		{11, 20, color.NRGBA{0, 255, 0, 255}, "medium"},
+		{21, math.MaxInt64, color.NRGBA{0, 0, 255, 255}, "high"},
+	}
+
+	tests := map[string]string{
+		"qgis.txt": `INTERPOLATION:DISCRETE
+10,255,0,0,255,low
+20.5,0,255,0,255,medium
+inf,0,0,255,255,high
+`,
+		"intervals.sld": `<ColorMap type="intervals">
+  <ColorMapEntry color="#FF0000" quantity="11" label="low"/>
+  <ColorMapEntry color="#00FF00" quantity="20.5" label="medium"/>
+  <ColorMapEntry color="#0000FF" quantity="1e30" label="high"/>
+</ColorMap>`,
+		"ranges.json": `{"entries": [
+	{"min": -1e30, "max": 10, "color": "#F00", "label": "low"},
+	{"min": 11, "max": 20, "color": "#0F0", "label": "medium"},
+	{"min": 21, "max": 1e30, "color": "#00F", "label": "high"}
+]}`,
+	}
+
+	for name, content := range tests {
+		colormap, err := ReadColormap(writeColormapFile(t, name, content))
+		if err != nil {
+			t.Errorf("%v: %v", name, err)
+			continue
+		}
+		checkEntries(t, name, colormap, expected)
+	}
+}
+
+func TestReadColormapGradient(t *testing.T) {
+	tests := map[string]string{
+		"qgis.txt": `INTERPOLATION:INTERPOLATED
+0.5,0,0,0,255,low
+100.5,255,255,255,255,high
+`,
+		"ramp.sld": `<ColorMap>
+  <ColorMapEntry color="#000000" quantity="0.5"/>
+  <ColorMapEntry color="#FFFFFF" quantity="100.5"/>
+</ColorMap>`,
+		"gradient.json": `{"type": "gradient", "entries": [{"value": 0.5, "color": "#000"}, {"value": 100.5, "color": "#FFF"}]}`,
+	}
+
+	for name, content := range tests {
+		colormap, err := ReadColormap(writeColormapFile(t, name, content))
+		if err != nil {
+			t.Errorf("%v: %v", name, err)
+			continue
+		}
+		if colormap.Gradient() == nil {
+			t.Errorf("%v: colormap is not a gradient", name)
+			continue
+		}
+		if c := colormap.Gradient().Color(50.5); c != (color.NRGBA{128, 128, 128, 255}) {
+			t.Errorf("%v: color %v of middle value does not match expected value", name, c)
+		}
+	}
+}
+
+func TestReadColormapInvalid(t *testing.T) {
+	tests := map[string]string{
+		"float.txt":      "1.5 255 0 0\n",
+		"percent.txt":    "50% 255 0 0\n",
+		"color.txt":      "1 255 0\n",
+		"duplicate.txt":  "1 255 0 0\n1 0 0 0\n",
+		"order.txt":      "INTERPOLATION:DISCRETE\n10,255,0,0,255,a\n5,0,0,0,255,b\n",
+		"range.json":     `{"entries": [{"min": 10, "max": 5, "color": "#000000"}]}`,
+		"noentries.json": `{"entries": []}`,
+		"novalue.json":   `{"entries": [{"color": "#000000"}]}`,
+	}
+
+	for name, content := range tests {
+		if _, err := ReadColormap(writeColormapFile(t, name, content)); err == nil {
+			t.Errorf("%v: invalid colormap did not raise error", name)
+		}
+	}
+}
+
+func TestColormapMissing(t *testing.T) {
+	colormap, err := NewColormap("1:#FF0000,2:#00FF00")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counts := make([]uint64, 256)
+	counts[1] = 10
+	counts[3] = 5
+	counts[200] = 1
+	missing := colormap.Missing(counts)
+	expected := []ValueCount{{3, 5}, {200, 1}}
+	if len(missing) != len(expected) {
+		t.Fatalf("missing values %v do not match expected values %v", missing, expected)
+	}
+	for i := range expected {
+		if missing[i] != expected[i] {
+			t.Errorf("missing value %v does not match expected value %v", missing[i], expected[i])
+		}
+	}
+}
+
+func TestColormapSharedColors(t *testing.T) {
+	// a full color table with repeated colors fits in a palette
+	entries := make([]ColormapEntry, 256)
+	for i := range entries {
+		entries[i] = ColormapEntry{Min: int64(i), Max: int64(i), Color: color.NRGBA{0, 0, 0, 255}}
+	}
+	entries[1].Color = color.NRGBA{255, 0, 0, 255}
+	colormap, err := NewColormapFromEntries(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := len(colormap.Palette()); size != 3 {
+		t.Errorf("palette size %v does not match expected size 3", size)
+	}
+	encoder, err := NewEncoder("uint8", 1, 2, 1, colormap, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := encoder.(*ColormapEncoder); !ok {
+		t.Errorf("encoder is not ColormapEncoder: %T", encoder)
+	}
+
+	// otherwise uint8 data are encoded to RGBA
+	for i := range entries {
+		entries[i].Color = color.NRGBA{uint8(i), 0, 0, 255}
+	}
+	colormap, err = NewColormapFromEntries(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoder, err = NewEncoder("uint8", 1, 2, 1, colormap, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := encoder.Render([]uint8{1, 255}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []color.NRGBA{{1, 0, 0, 255}, {255, 0, 0, 255}}
+	for col, c := range expected {
+		if value := color.NRGBAModel.Convert(img.At(col, 0)); value != c {
+			t.Errorf("pixel %v: %v does not match expected value %v", col, value, c)
+		}
+	}
+}
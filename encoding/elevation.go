@@ -0,0 +1,98 @@
+package encoding
+
+import (
+	"image"
+	"math"
+
+	"github.com/brendan-ward/rastertiler/array"
+)
+
+// Default base and interval of Mapbox Terrain-RGB, where
+// height = -10000 + ((R * 256 * 256 + G * 256 + B) * 0.1)
+const TerrainRGBBase float64 = -10000
+const TerrainRGBInterval float64 = 0.1
+
+// Default base and interval of Terrarium, where
+// height = (R * 256 + G + B / 256) - 32768
+const TerrariumBase float64 = -32768
+const TerrariumInterval float64 = 1.0 / 256
+
+const maxPackedElevation = 1<<24 - 1
+
+// ElevationEncoder packs elevation values into the RGB channels of a PNG as
+// a 24-bit integer: (height - base) / interval.  Terrain-RGB and Terrarium
+// both use this packing, with different base and interval.
+type ElevationEncoder struct {
+	pngEncoder
+	img      *image.NRGBA
+	width    int
+	height   int
+	base     float64
+	interval float64
+	nodata   interface{}
+}
+
+// Create an ElevationEncoder with base and interval, such as those of
+// Terrain-RGB or Terrarium.  Nodata is optional; if provided, nodata and NaN
+// values are encoded as a height of 0.
+func NewElevationEncoder(width int, height int, base float64, interval float64, nodata interface{}) *ElevationEncoder {
+	return &ElevationEncoder{
+		img:      image.NewNRGBA(image.Rect(0, 0, width, height)),
+		width:    width,
+		height:   height,
+		base:     base,
+		interval: interval,
+		nodata:   nodata,
+	}
+}
+
+// Pack height into red, green, and blue values.  Heights outside the range
+// that can be packed are clamped.
+func (e *ElevationEncoder) Pack(height float64) (r uint8, g uint8, b uint8) {
+	value := math.Round((height - e.base) / e.interval)
+	value = math.Min(math.Max(value, 0), maxPackedElevation)
+	packed := uint32(value)
+	return uint8(packed >> 16), uint8(packed >> 8), uint8(packed)
+}
+
+// Unpack height from red, green, and blue values
+func (e *ElevationEncoder) Unpack(r uint8, g uint8, b uint8) float64 {
+	return e.base + float64(uint32(r)<<16|uint32(g)<<8|uint32(b))*e.interval
+}
+
+// Render elevation values of any numeric dtype to 24-bit RGB image.  Pixels
+// that are transparent in mask are encoded as a height of 0, because
+// elevation tiles must be opaque.
+func (e *ElevationEncoder) Render(buffer interface{}, mask []uint8) (image.Image, error) {
+	get, _ := array.Accessors(buffer)
+
+	hasNodata := e.nodata != nil
+	var nodata float64
+	if hasNodata {
+		nodata = array.ToFloat(e.nodata)
+	}
+
+	var value float64
+	for i := 0; i < e.width*e.height; i++ {
+		value = get(i)
+		if math.IsNaN(value) || (hasNodata && value == nodata) || (mask != nil && mask[i] == 0) {
+			value = 0
+		}
+		r, g, b := e.Pack(value)
+		e.img.Pix[4*i] = r
+		e.img.Pix[4*i+1] = g
+		e.img.Pix[4*i+2] = b
+		e.img.Pix[4*i+3] = 255
+	}
+
+	return e.img, nil
+}
+
+// Encode buffer to PNG
+func (e *ElevationEncoder) Encode(buffer interface{}, mask []uint8) ([]byte, error) {
+	img, err := e.Render(buffer, mask)
+	if err != nil {
+		return nil, err
+	}
+	return e.encode(img)
+}
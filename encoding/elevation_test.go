@@ -0,0 +1,80 @@
+package encoding
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"math"
+	"testing"
+)
+
+func decodeElevations(t *testing.T, encoder *ElevationEncoder, data []byte) []float64 {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bounds := img.Bounds()
+	heights := make([]float64, 0, bounds.Dx()*bounds.Dy())
+	for row := 0; row < bounds.Dy(); row++ {
+		for col := 0; col < bounds.Dx(); col++ {
+			// all pixels are opaque, so decoded as RGBA
+			c := img.(*image.RGBA).RGBAAt(col, row)
+			heights = append(heights, encoder.Unpack(c.R, c.G, c.B))
+		}
+	}
+	return heights
+}
+
+func TestTerrainRGBEncoder(t *testing.T) {
+	encoder := NewElevationEncoder(3, 2, TerrainRGBBase, TerrainRGBInterval, float32(-9999))
+
+	// elevations at precision of interval, including lowest and highest
+	// elevations on Earth
+	elevations := []float32{-10000, -428.3, 0, 1234.5, 8848.8, -9999}
+	expected := []float64{-10000, -428.3, 0, 1234.5, 8848.8, 0}
+
+	data, err := encoder.Encode(elevations, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, height := range decodeElevations(t, encoder, data) {
+		if math.Abs(height-expected[i]) > 1e-3 {
+			t.Errorf("height %v: %v does not match expected height %v", i, height, expected[i])
+		}
+	}
+
+	// Mapbox example: RGB 1, 134, 160 is 0m
+	r, g, b := encoder.Pack(0)
+	if r != 1 || g != 134 || b != 160 {
+		t.Errorf("0m packed to unexpected RGB: %v, %v, %v", r, g, b)
+	}
+}
+
+func TestTerrariumEncoder(t *testing.T) {
+	encoder := NewElevationEncoder(3, 2, TerrariumBase, TerrariumInterval, nil)
+
+	// int16 elevations are always exact
+	elevations := []int16{-32768, -428, 0, 1234, 8848, 32767}
+
+	data, err := encoder.Encode(elevations, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, height := range decodeElevations(t, encoder, data) {
+		if height != float64(elevations[i]) {
+			t.Errorf("height %v: %v does not match expected height %v", i, height, elevations[i])
+		}
+	}
+
+	// fractional elevations at precision of 1/256 are exact
+	r, g, b := encoder.Pack(100.25)
+	if height := encoder.Unpack(r, g, b); height != 100.25 {
+		t.Errorf("100.25m round trip returned unexpected height: %v", height)
+	}
+
+	// Terrarium example: 0m is RGB 128, 0, 0
+	r, g, b = encoder.Pack(0)
+	if r != 128 || g != 0 || b != 0 {
+		t.Errorf("0m packed to unexpected RGB: %v, %v, %v", r, g, b)
+	}
+}
@@ -0,0 +1,330 @@
+package encoding
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/brendan-ward/rastertiler/array"
+)
+
+// ColorSpace used to interpolate colors between gradient stops
+type ColorSpace int
+
+const (
+	// interpolate sRGB values directly
+	RGB ColorSpace = iota
+	// interpolate in the perceptually uniform OKLab color space
+	OKLab
+)
+
+type gradientStop struct {
+	value float64
+	color color.NRGBA
+	lab   [3]float64 // OKLab coordinates of color
+}
+
+// Gradient is a continuous colormap that interpolates colors between ordered
+// stops
+type Gradient struct {
+	stops []gradientStop
+	space ColorSpace
+	clamp bool // if false, values outside stops are transparent
+}
+
+// Return true if colormap string is a gradient
+func IsGradient(colormap string) bool {
+	return strings.HasPrefix(strings.TrimSpace(colormap), "gradient")
+}
+
+// Create new gradient by parsing gradient string, which is a comma-delimited
+// set of "gradient" followed by options and <value>:<hex> stops in ascending
+// order of value, e.g., "gradient,oklab,transparent,0:#440154,100:#fde725".
+//
+// Options are the color space used to interpolate: rgb (default) or oklab,
+// and how values outside the stops are rendered: clamp (default) to use the
+// color of the nearest stop, or transparent.
+func NewGradient(gradient string) (*Gradient, error) {
+	entries := strings.Split(strings.ReplaceAll(gradient, " ", ""), ",")
+	if entries[0] != "gradient" {
+		return nil, fmt.Errorf("gradient must start with 'gradient'")
+	}
+
+	g := &Gradient{
+		space: RGB,
+		clamp: true,
+	}
+	for _, entry := range entries[1:] {
+		switch entry {
+		case "rgb":
+			g.space = RGB
+		case "oklab":
+			g.space = OKLab
+		case "clamp":
+			g.clamp = true
+		case "transparent":
+			g.clamp = false
+		default:
+			parts := strings.Split(entry, ":")
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("invalid gradient entry: %v", entry)
+			}
+			value, err := strconv.ParseFloat(parts[0], 64)
+			if err != nil {
+				return nil, err
+			}
+			c, err := ParseColor(parts[1])
+			if err != nil {
+				return nil, err
+			}
+			if err = g.addStop(value, c); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := g.validate(); err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+// Add stop after existing stops
+func (g *Gradient) addStop(value float64, c color.NRGBA) error {
+	if len(g.stops) > 0 && value <= g.stops[len(g.stops)-1].value {
+		return fmt.Errorf("gradient stops must be in ascending order of value")
+	}
+	g.stops = append(g.stops, gradientStop{value: value, color: c, lab: toOKLab(c)})
+	return nil
+}
+
+func (g *Gradient) validate() error {
+	if len(g.stops) < 2 {
+		return fmt.Errorf("gradient must have at least 2 stops")
+	}
+	return nil
+}
+
+// Returns the color of value interpolated between stops.  NaN values are
+// transparent.
+func (g *Gradient) Color(value float64) color.NRGBA {
+	first := g.stops[0]
+	last := g.stops[len(g.stops)-1]
+
+	switch {
+	case math.IsNaN(value):
+		return color.NRGBA{}
+	case value < first.value || value > last.value:
+		if !g.clamp {
+			return color.NRGBA{}
+		}
+		if value < first.value {
+			return first.color
+		}
+		return last.color
+	}
+
+	// index of first stop with value greater than or equal to value
+	i := sort.Search(len(g.stops), func(i int) bool { return g.stops[i].value >= value })
+	if i == 0 {
+		return first.color
+	}
+	left := g.stops[i-1]
+	right := g.stops[i]
+	t := (value - left.value) / (right.value - left.value)
+
+	alpha := uint8(math.Round(float64(left.color.A) + t*(float64(right.color.A)-float64(left.color.A))))
+
+	if g.space == OKLab {
+		var lab [3]float64
+		for j := 0; j < 3; j++ {
+			lab[j] = left.lab[j] + t*(right.lab[j]-left.lab[j])
+		}
+		c := fromOKLab(lab)
+		c.A = alpha
+		return c
+	}
+
+	lerp := func(a uint8, b uint8) uint8 {
+		return uint8(math.Round(float64(a) + t*(float64(b)-float64(a))))
+	}
+	return color.NRGBA{lerp(left.color.R, right.color.R), lerp(left.color.G, right.color.G), lerp(left.color.B, right.color.B), alpha}
+}
+
+// Convert sRGB color to OKLab; see https://bottosson.github.io/posts/oklab/
+func toOKLab(c color.NRGBA) [3]float64 {
+	toLinear := func(v uint8) float64 {
+		x := float64(v) / 255
+		if x <= 0.04045 {
+			return x / 12.92
+		}
+		return math.Pow((x+0.055)/1.055, 2.4)
+	}
+	r, g, b := toLinear(c.R), toLinear(c.G), toLinear(c.B)
+
+	l := math.Cbrt(0.4122214708*r + 0.5363325363*g + 0.0514459929*b)
+	m := math.Cbrt(0.2119034982*r + 0.6806995451*g + 0.1073969566*b)
+	s := math.Cbrt(0.0883024619*r + 0.2817188376*g + 0.6299787005*b)
+
+	return [3]float64{
+		0.2104542553*l + 0.7936177850*m - 0.0040720468*s,
+		1.9779984951*l - 2.4285922050*m + 0.4505937099*s,
+		0.0259040371*l + 0.7827717662*m - 0.8086757660*s,
+	}
+}
+
+// Convert OKLab coordinates to opaque sRGB color
+func fromOKLab(lab [3]float64) color.NRGBA {
+	l := math.Pow(lab[0]+0.3963377774*lab[1]+0.2158037573*lab[2], 3)
+	m := math.Pow(lab[0]-0.1055613458*lab[1]-0.0638541728*lab[2], 3)
+	s := math.Pow(lab[0]-0.0894841775*lab[1]-1.2914855480*lab[2], 3)
+
+	fromLinear := func(x float64) uint8 {
+		if x <= 0.0031308 {
+			x = 12.92 * x
+		} else {
+			x = 1.055*math.Pow(x, 1/2.4) - 0.055
+		}
+		return uint8(math.Round(math.Min(math.Max(x, 0), 1) * 255))
+	}
+
+	return color.NRGBA{
+		fromLinear(4.0767416621*l - 3.3077115913*m + 0.2309699292*s),
+		fromLinear(-1.2684380046*l + 2.6097574011*m - 0.3413193965*s),
+		fromLinear(-0.0041960863*l - 0.7034186147*m + 1.7076147010*s),
+		255,
+	}
+}
+
+// GradientEncoder encodes single-band data of any dtype using a gradient.
+// 8-bit and 16-bit integer data are encoded to 8-bit paletted PNG if there
+// are no more than 256 distinct colors, otherwise data are encoded to RGBA
+// PNG.
+type GradientEncoder struct {
+	pngEncoder
+	gradient *Gradient
+	nodata   interface{}
+	lookup   []color.NRGBA // color of each value for 8-bit and 16-bit data
+	indexes  []uint8       // palette index of each value if paletted
+	offset   int           // offset of values into lookup and indexes
+	img      image.Image
+	width    int
+	height   int
+}
+
+// Create a GradientEncoder for dtype.  Nodata is optional; if provided,
+// nodata values are transparent.
+func NewGradientEncoder(width int, height int, gradient *Gradient, dtype string, nodata interface{}) *GradientEncoder {
+	e := &GradientEncoder{
+		gradient: gradient,
+		nodata:   nodata,
+		width:    width,
+		height:   height,
+	}
+
+	var size int
+	switch dtype {
+	case "int8":
+		size = math.MaxUint8 + 1
+		e.offset = -math.MinInt8
+	case "uint8":
+		size = math.MaxUint8 + 1
+	case "int16":
+		size = math.MaxUint16 + 1
+		e.offset = -math.MinInt16
+	case "uint16":
+		size = math.MaxUint16 + 1
+	default:
+		e.img = image.NewNRGBA(image.Rect(0, 0, width, height))
+		return e
+	}
+
+	// precompute colors of all possible values
+	e.lookup = make([]color.NRGBA, size)
+	for i := 0; i < size; i++ {
+		e.lookup[i] = gradient.Color(float64(i - e.offset))
+	}
+	if nodata != nil {
+		e.lookup[int(array.ToFloat(nodata))+e.offset] = color.NRGBA{}
+	}
+
+	// use a palette if possible; the first color is transparent, for pixels
+	// that are transparent in the mask
+	palette := color.Palette{color.NRGBA{}}
+	paletteIndexes := map[color.NRGBA]uint8{{}: 0}
+	indexes := make([]uint8, size)
+	for i, c := range e.lookup {
+		index, ok := paletteIndexes[c]
+		if !ok {
+			if len(palette) == 256 {
+				e.img = image.NewNRGBA(image.Rect(0, 0, width, height))
+				return e
+			}
+			index = uint8(len(palette))
+			paletteIndexes[c] = index
+			palette = append(palette, c)
+		}
+		indexes[i] = index
+	}
+	e.indexes = indexes
+	e.img = image.NewPaletted(image.Rect(0, 0, width, height), palette)
+
+	return e
+}
+
+// Render values to paletted or RGBA image.  Pixels that are fully transparent
+// in mask are transparent; alpha is scaled by mask for RGBA.
+func (e *GradientEncoder) Render(buffer interface{}, mask []uint8) (image.Image, error) {
+	get, _ := array.Accessors(buffer)
+
+	switch img := e.img.(type) {
+	case *image.Paletted:
+		for i := 0; i < e.width*e.height; i++ {
+			if mask != nil && mask[i] == 0 {
+				img.Pix[i] = 0
+				continue
+			}
+			img.Pix[i] = e.indexes[int(get(i))+e.offset]
+		}
+	case *image.NRGBA:
+		hasNodata := e.nodata != nil
+		var nodata float64
+		if hasNodata {
+			nodata = array.ToFloat(e.nodata)
+		}
+
+		var value float64
+		var c color.NRGBA
+		for i := 0; i < e.width*e.height; i++ {
+			value = get(i)
+			switch {
+			case e.lookup != nil:
+				c = e.lookup[int(value)+e.offset]
+			case hasNodata && (value == nodata || (math.IsNaN(nodata) && math.IsNaN(value))):
+				c = color.NRGBA{}
+			default:
+				c = e.gradient.Color(value)
+			}
+			img.Pix[4*i] = c.R
+			img.Pix[4*i+1] = c.G
+			img.Pix[4*i+2] = c.B
+			img.Pix[4*i+3] = maskAlpha(c.A, mask, i)
+		}
+	}
+
+	return e.img, nil
+}
+
+// Encode buffer to PNG
+func (e *GradientEncoder) Encode(buffer interface{}, mask []uint8) ([]byte, error) {
+	img, err := e.Render(buffer, mask)
+	if err != nil {
+		return nil, err
+	}
+	return e.encode(img)
+}
@@ -0,0 +1,134 @@
+package encoding
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"testing"
+)
+
+func TestNewGradient(t *testing.T) {
+	gradient, err := NewGradient("gradient, 0:#000000, 10:#FFFFFF, 20:#FF0000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[float64]color.NRGBA{
+		-5:         {0, 0, 0, 255},
+		0:          {0, 0, 0, 255},
+		5:          {128, 128, 128, 255},
+		10:         {255, 255, 255, 255},
+		15:         {255, 128, 128, 255},
+		20:         {255, 0, 0, 255},
+		25:         {255, 0, 0, 255},
+		math.NaN(): {},
+	}
+	for value, c := range expected {
+		if gradient.Color(value) != c {
+			t.Errorf("value %v: %v does not match expected color %v", value, gradient.Color(value), c)
+		}
+	}
+
+	gradient, err = NewGradient("gradient,transparent,0:#000000,10:#FFFFFF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := gradient.Color(-1); c != (color.NRGBA{}) {
+		t.Errorf("value outside gradient is not transparent: %v", c)
+	}
+	if c := gradient.Color(11); c != (color.NRGBA{}) {
+		t.Errorf("value outside gradient is not transparent: %v", c)
+	}
+
+	invalid := []string{
+		"gradient,0:#000000",
+		"gradient,10:#000000,0:#FFFFFF",
+		"gradient,0:#000000,10:#FFFFFF,foo",
+		"0:#000000,10:#FFFFFF",
+	}
+	for _, value := range invalid {
+		if _, err = NewGradient(value); err == nil {
+			t.Errorf("NewGradient() did not return error for %v", value)
+		}
+	}
+}
+
+func TestGradientOKLab(t *testing.T) {
+	gradient, err := NewGradient("gradient,oklab,0:#000000,10:#FFFFFF")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// stops are preserved exactly
+	if c := gradient.Color(0); c != (color.NRGBA{0, 0, 0, 255}) {
+		t.Errorf("unexpected color at first stop: %v", c)
+	}
+	if c := gradient.Color(10); c != (color.NRGBA{255, 255, 255, 255}) {
+		t.Errorf("unexpected color at last stop: %v", c)
+	}
+
+	// perceptual midpoint of black and white (L = 0.5) differs from RGB midpoint
+	if c := gradient.Color(5); c != (color.NRGBA{99, 99, 99, 255}) {
+		t.Errorf("unexpected color at midpoint: %v", c)
+	}
+}
+
+func TestGradientEncoder(t *testing.T) {
+	gradient, err := NewGradient("gradient,0:#000000,10:#FFFFFF")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// uint8 data are paletted, nodata is transparent
+	encoder := NewGradientEncoder(3, 1, gradient, "uint8", uint8(255))
+	data, err := encoder.Encode([]uint8{0, 5, 255}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := img.(*image.Paletted); !ok {
+		t.Errorf("uint8 data not encoded to paletted PNG: %T", img)
+	}
+	expected := []color.NRGBA{{0, 0, 0, 255}, {128, 128, 128, 255}, {}}
+	for col, c := range expected {
+		if value := color.NRGBAModel.Convert(img.At(col, 0)); value != c {
+			t.Errorf("pixel %v: %v does not match expected color %v", col, value, c)
+		}
+	}
+
+	// float data are RGBA
+	nan := float32(math.NaN())
+	encoder = NewGradientEncoder(3, 1, gradient, "float32", nan)
+	data, err = encoder.Encode([]float32{0, 2.5, nan}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err = png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []color.NRGBA{{0, 0, 0, 255}, {64, 64, 64, 255}, {}}
+	for col, c := range expected {
+		if value := color.NRGBAModel.Convert(img.At(col, 0)); value != c {
+			t.Errorf("pixel %v: %v does not match expected color %v", col, value, c)
+		}
+	}
+
+	// int16 data are looked up with an offset
+	encoder = NewGradientEncoder(3, 1, gradient, "int16", int16(-32768))
+	img, err = encoder.Render([]int16{-10, 5, -32768}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []color.NRGBA{{0, 0, 0, 255}, {128, 128, 128, 255}, {}}
+	for col, c := range expected {
+		if value := color.NRGBAModel.Convert(img.At(col, 0)); value != c {
+			t.Errorf("pixel %v: %v does not match expected color %v", col, value, c)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package encoding
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"hash/crc32"
+	"math"
+	"math/bits"
+)
+
+// PNG color types; see https://www.w3.org/TR/png/#6Colour-values
+const (
+	pngColorGray      = 0
+	pngColorGrayAlpha = 4
+)
+
+const pngSignature = "\x89PNG\r\n\x1a\n"
+
+// Encode grayscale samples with transparency to PNG, which is not supported
+// by image/png.  Samples are 8-bit or big-endian 16-bit values according to
+// depth, and alpha has an 8-bit value for each pixel.  If pixels are only
+// fully transparent or opaque and there is a gray value that is not used by
+// any opaque pixel, transparent pixels are set to that value and marked as
+// transparent in a tRNS chunk; otherwise pixels are encoded as gray+alpha.
+func (e *pngEncoder) encodeGray(samples []uint8, alpha []uint8, width int, height int, depth int) ([]byte, error) {
+	sampleSize := depth / 8
+	key, hasKey := transparentKey(samples, alpha, sampleSize)
+
+	colorType := pngColorGrayAlpha
+	pixelSize := 2 * sampleSize
+	if hasKey {
+		colorType = pngColorGray
+		pixelSize = sampleSize
+	}
+
+	e.pngBuffer.Reset()
+	e.pngBuffer.WriteString(pngSignature)
+
+	// compression, filter, and interlace methods are 0
+	header := make([]byte, 13)
+	binary.BigEndian.PutUint32(header[0:4], uint32(width))
+	binary.BigEndian.PutUint32(header[4:8], uint32(height))
+	header[8] = uint8(depth)
+	header[9] = uint8(colorType)
+	writeChunk(&e.pngBuffer, "IHDR", header)
+
+	if hasKey {
+		trns := make([]byte, 2)
+		binary.BigEndian.PutUint16(trns, key)
+		writeChunk(&e.pngBuffer, "tRNS", trns)
+	}
+
+	var data bytes.Buffer
+	z := zlib.NewWriter(&data)
+
+	rowSize := width * pixelSize
+	row := make([]uint8, rowSize)
+	prev := make([]uint8, rowSize)
+	var filtered [5][]uint8
+	for f := range filtered {
+		filtered[f] = make([]uint8, 1+rowSize)
+		filtered[f][0] = uint8(f)
+	}
+
+	for r := 0; r < height; r++ {
+		for c := 0; c < width; c++ {
+			i := r*width + c
+			pixel := row[c*pixelSize : (c+1)*pixelSize]
+			switch {
+			case hasKey && alpha[i] == 0:
+				if sampleSize == 2 {
+					binary.BigEndian.PutUint16(pixel, key)
+				} else {
+					pixel[0] = uint8(key)
+				}
+			default:
+				copy(pixel, samples[i*sampleSize:(i+1)*sampleSize])
+				if !hasKey {
+					// 16-bit alpha is scaled from 8-bit alpha by repeating it
+					for j := sampleSize; j < pixelSize; j++ {
+						pixel[j] = alpha[i]
+					}
+				}
+			}
+		}
+
+		if _, err := z.Write(filterRow(&filtered, row, prev, pixelSize)); err != nil {
+			return nil, err
+		}
+		row, prev = prev, row
+	}
+	if err := z.Close(); err != nil {
+		return nil, err
+	}
+
+	writeChunk(&e.pngBuffer, "IDAT", data.Bytes())
+	writeChunk(&e.pngBuffer, "IEND", nil)
+
+	return e.pngBuffer.Bytes(), nil
+}
+
+// Find a gray value that is not used by any opaque pixel, if pixels are only
+// fully transparent or opaque
+func transparentKey(samples []uint8, alpha []uint8, sampleSize int) (uint16, bool) {
+	// bitset of used values
+	used := make([]uint64, (1<<(8*sampleSize))/64)
+	var value uint16
+	for i, a := range alpha {
+		switch a {
+		case 0:
+			continue
+		case 255:
+			if sampleSize == 2 {
+				value = binary.BigEndian.Uint16(samples[2*i:])
+			} else {
+				value = uint16(samples[i])
+			}
+			used[value/64] |= 1 << (value % 64)
+		default:
+			return 0, false
+		}
+	}
+
+	for i, set := range used {
+		if set != math.MaxUint64 {
+			return uint16(i*64 + bits.TrailingZeros64(^set)), true
+		}
+	}
+	return 0, false
+}
+
+// Filter row using each PNG filter type, and return the filter type followed
+// by the filtered row with the smallest sum of absolute values, which is the
+// heuristic recommended by the PNG specification.  Prev is the previous
+// unfiltered row, and bpp is the number of bytes per pixel.
+func filterRow(filtered *[5][]uint8, row []uint8, prev []uint8, bpp int) []uint8 {
+	var a, b, c uint8
+	for i, x := range row {
+		a, c = 0, 0
+		if i >= bpp {
+			a = row[i-bpp]
+			c = prev[i-bpp]
+		}
+		b = prev[i]
+
+		filtered[0][i+1] = x
+		filtered[1][i+1] = x - a
+		filtered[2][i+1] = x - b
+		filtered[3][i+1] = x - uint8((int(a)+int(b))/2)
+		filtered[4][i+1] = x - paeth(a, b, c)
+	}
+
+	best := 0
+	bestSum := math.MaxInt64
+	for f := range filtered {
+		sum := 0
+		for _, v := range filtered[f][1:] {
+			if v < 128 {
+				sum += int(v)
+			} else {
+				sum += 256 - int(v)
+			}
+		}
+		if sum < bestSum {
+			best = f
+			bestSum = sum
+		}
+	}
+	return filtered[best]
+}
+
+// Paeth predictor of a (left), b (up), and c (upper left)
+func paeth(a uint8, b uint8, c uint8) uint8 {
+	p := int(a) + int(b) - int(c)
+	pa := abs(p - int(a))
+	pb := abs(p - int(b))
+	pc := abs(p - int(c))
+	if pa <= pb && pa <= pc {
+		return a
+	}
+	if pb <= pc {
+		return b
+	}
+	return c
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// Write a PNG chunk with its length and CRC
+func writeChunk(w *bytes.Buffer, name string, data []byte) {
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header[:4], uint32(len(data)))
+	copy(header[4:], name)
+	w.Write(header)
+	w.Write(data)
+
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(data)
+	footer := make([]byte, 4)
+	binary.BigEndian.PutUint32(footer, crc.Sum32())
+	w.Write(footer)
+}
@@ -1,41 +1,195 @@
 package encoding
 
 import (
-	"bytes"
 	"image"
-	"image/png"
 )
 
+// GrayscaleEncoder encodes 8-bit values to grayscale PNG.  Transparent pixels
+// are encoded using the smallest PNG color type that supports them: grayscale
+// with a transparent gray value or gray+alpha.
 type GrayscaleEncoder struct {
+	pngEncoder
 	img       *image.Gray
-	pngBuffer bytes.Buffer
+	alpha     []uint8
+	maskedImg *image.NRGBA // rendered if there are transparent pixels
 	width     int
 	height    int
+	nodata    interface{}
 }
 
-func NewGrayscaleEncoder(width int, height int) *GrayscaleEncoder {
+// Create a GrayscaleEncoder.  Nodata is optional; if provided, nodata values
+// are transparent.
+func NewGrayscaleEncoder(width int, height int, nodata interface{}) *GrayscaleEncoder {
 	return &GrayscaleEncoder{
-		img:    image.NewGray(image.Rect(0, 0, width, height)),
-		width:  width,
-		height: height,
+		img:       image.NewGray(image.Rect(0, 0, width, height)),
+		alpha:     make([]uint8, width*height),
+		maskedImg: image.NewNRGBA(image.Rect(0, 0, width, height)),
+		width:     width,
+		height:    height,
+		nodata:    nodata,
 	}
 }
 
-// Encode uint8 values to 8-bit grayscale PNG
-func (e *GrayscaleEncoder) Encode(buffer interface{}) ([]byte, error) {
+// Set gray values and alpha from buffer and mask; returns true if there are
+// transparent pixels.  int8 values are offset by 128 so that -128 is black
+// and 127 is white.
+func (e *GrayscaleEncoder) render(buffer interface{}, mask []uint8) bool {
+	for i := range e.alpha {
+		e.alpha[i] = maskAlpha(255, mask, i)
+	}
+
 	switch typedBuffer := buffer.(type) {
+	case []int8:
+		hasNodata := e.nodata != nil
+		var nodata int8
+		if hasNodata {
+			nodata = e.nodata.(int8)
+		}
+		for i, value := range typedBuffer[:len(e.img.Pix)] {
+			e.img.Pix[i] = uint8(value) ^ 0x80
+			if hasNodata && value == nodata {
+				e.alpha[i] = 0
+			}
+		}
 	case []uint8:
-		for row := 0; row < e.height; row++ {
-			for col := 0; col < e.width; col++ {
-				e.img.Pix[row*e.width+col] = typedBuffer[row*e.width+col]
+		hasNodata := e.nodata != nil
+		var nodata uint8
+		if hasNodata {
+			nodata = e.nodata.(uint8)
+		}
+		for i, value := range typedBuffer[:len(e.img.Pix)] {
+			e.img.Pix[i] = value
+			if hasNodata && value == nodata {
+				e.alpha[i] = 0
+			}
+		}
+	default:
+		panic("Other dtypes not yet supported for GrayscaleEncoder::Encode()")
+	}
+
+	return hasTransparency(e.alpha)
+}
+
+// Render 8-bit values to grayscale image, or to RGBA if there are
+// transparent pixels
+func (e *GrayscaleEncoder) Render(buffer interface{}, mask []uint8) (image.Image, error) {
+	if !e.render(buffer, mask) {
+		return e.img, nil
+	}
+
+	for i, value := range e.img.Pix {
+		e.maskedImg.Pix[4*i] = value
+		e.maskedImg.Pix[4*i+1] = value
+		e.maskedImg.Pix[4*i+2] = value
+		e.maskedImg.Pix[4*i+3] = e.alpha[i]
+	}
+	return e.maskedImg, nil
+}
+
+// Encode buffer to grayscale PNG, with transparency if needed
+func (e *GrayscaleEncoder) Encode(buffer interface{}, mask []uint8) ([]byte, error) {
+	if !e.render(buffer, mask) {
+		return e.encode(e.img)
+	}
+	return e.encodeGray(e.img.Pix, e.alpha, e.width, e.height, 8)
+}
+
+// Grayscale16Encoder encodes 16-bit values to 16-bit grayscale PNG, using the
+// same color types as GrayscaleEncoder for transparent pixels
+type Grayscale16Encoder struct {
+	pngEncoder
+	img       *image.Gray16
+	alpha     []uint8
+	maskedImg *image.NRGBA64 // rendered if there are transparent pixels
+	width     int
+	height    int
+	nodata    interface{}
+}
+
+// Create a Grayscale16Encoder.  Nodata is optional; if provided, nodata
+// values are transparent.
+func NewGrayscale16Encoder(width int, height int, nodata interface{}) *Grayscale16Encoder {
+	return &Grayscale16Encoder{
+		img:       image.NewGray16(image.Rect(0, 0, width, height)),
+		alpha:     make([]uint8, width*height),
+		maskedImg: image.NewNRGBA64(image.Rect(0, 0, width, height)),
+		width:     width,
+		height:    height,
+		nodata:    nodata,
+	}
+}
+
+// Set gray values and alpha from buffer and mask; returns true if there are
+// transparent pixels.  int16 values are offset by 32768 so that -32768 is
+// black and 32767 is white.
+func (e *Grayscale16Encoder) render(buffer interface{}, mask []uint8) bool {
+	for i := range e.alpha {
+		e.alpha[i] = maskAlpha(255, mask, i)
+	}
+
+	var value uint16
+	switch typedBuffer := buffer.(type) {
+	case []int16:
+		hasNodata := e.nodata != nil
+		var nodata int16
+		if hasNodata {
+			nodata = e.nodata.(int16)
+		}
+		for i := range e.alpha {
+			value = uint16(typedBuffer[i]) ^ 0x8000
+			// big-endian
+			e.img.Pix[2*i] = uint8(value >> 8)
+			e.img.Pix[2*i+1] = uint8(value)
+			if hasNodata && typedBuffer[i] == nodata {
+				e.alpha[i] = 0
 			}
 		}
+	case []uint16:
+		hasNodata := e.nodata != nil
+		var nodata uint16
+		if hasNodata {
+			nodata = e.nodata.(uint16)
+		}
+		for i := range e.alpha {
+			value = typedBuffer[i]
+			// big-endian
+			e.img.Pix[2*i] = uint8(value >> 8)
+			e.img.Pix[2*i+1] = uint8(value)
+			if hasNodata && value == nodata {
+				e.alpha[i] = 0
+			}
+		}
+	default:
+		panic("Other dtypes not yet supported for Grayscale16Encoder::Encode()")
+	}
+
+	return hasTransparency(e.alpha)
+}
+
+// Render 16-bit values to 16-bit grayscale image, or to 64-bit RGBA if there
+// are transparent pixels
+func (e *Grayscale16Encoder) Render(buffer interface{}, mask []uint8) (image.Image, error) {
+	if !e.render(buffer, mask) {
+		return e.img, nil
 	}
 
-	e.pngBuffer.Reset()
-	err := png.Encode(&e.pngBuffer, e.img)
-	if err != nil {
-		return nil, err
+	// NRGBA64 pixels are 8 bytes of big-endian red, green, blue, alpha
+	for i, alpha := range e.alpha {
+		hi, lo := e.img.Pix[2*i], e.img.Pix[2*i+1]
+		for j := 0; j < 3; j++ {
+			e.maskedImg.Pix[8*i+2*j] = hi
+			e.maskedImg.Pix[8*i+2*j+1] = lo
+		}
+		e.maskedImg.Pix[8*i+6] = alpha
+		e.maskedImg.Pix[8*i+7] = alpha
+	}
+	return e.maskedImg, nil
+}
+
+// Encode buffer to 16-bit grayscale PNG, with transparency if needed
+func (e *Grayscale16Encoder) Encode(buffer interface{}, mask []uint8) ([]byte, error) {
+	if !e.render(buffer, mask) {
+		return e.encode(e.img)
 	}
-	return e.pngBuffer.Bytes(), nil
+	return e.encodeGray(e.img.Pix, e.alpha, e.width, e.height, 16)
 }
@@ -0,0 +1,169 @@
+package encoding
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func TestGrayscale16Encoder(t *testing.T) {
+	encoder := NewGrayscale16Encoder(3, 1, nil)
+	values := []uint16{0, 256, 65535}
+	data, err := encoder.Encode(values, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gray, ok := img.(*image.Gray16)
+	if !ok {
+		t.Fatalf("decoded image is not Gray16: %T", img)
+	}
+	for col, expected := range values {
+		if value := gray.Gray16At(col, 0).Y; value != expected {
+			t.Errorf("pixel %v: %v does not match expected value %v", col, value, expected)
+		}
+	}
+}
+
+func TestGrayscaleEncoderSigned(t *testing.T) {
+	img, err := NewGrayscaleEncoder(3, 1, nil).Render([]int8{-128, 0, 127}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gray := img.(*image.Gray)
+	for col, expected := range []uint8{0, 128, 255} {
+		if value := gray.GrayAt(col, 0).Y; value != expected {
+			t.Errorf("int8 pixel %v: %v does not match expected value %v", col, value, expected)
+		}
+	}
+
+	img, err = NewGrayscale16Encoder(3, 1, nil).Render([]int16{-32768, 0, 32767}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gray16 := img.(*image.Gray16)
+	for col, expected := range []uint16{0, 32768, 65535} {
+		if value := gray16.Gray16At(col, 0).Y; value != expected {
+			t.Errorf("int16 pixel %v: %v does not match expected value %v", col, value, expected)
+		}
+	}
+}
+
+func TestGrayscaleEncoderMask(t *testing.T) {
+	encoder := NewGrayscaleEncoder(3, 1, nil)
+	img, err := encoder.Render([]uint8{10, 20, 30}, []uint8{255, 255, 255})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := img.(*image.Gray); !ok {
+		t.Errorf("opaque mask not rendered to grayscale image: %T", img)
+	}
+
+	img, err = encoder.Render([]uint8{10, 20, 30}, []uint8{255, 0, 128})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []color.NRGBA{{10, 10, 10, 255}, {20, 20, 20, 0}, {30, 30, 30, 128}}
+	for col, c := range expected {
+		if value := img.(*image.NRGBA).NRGBAAt(col, 0); value != c {
+			t.Errorf("pixel %v: %v does not match expected value %v", col, value, c)
+		}
+	}
+
+	img, err = NewGrayscale16Encoder(2, 1, nil).Render([]uint16{1000, 2000}, []uint8{255, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected16 := []color.NRGBA64{{1000, 1000, 1000, 65535}, {2000, 2000, 2000, 0}}
+	for col, c := range expected16 {
+		if value := img.(*image.NRGBA64).NRGBA64At(col, 0); value != c {
+			t.Errorf("16-bit pixel %v: %v does not match expected value %v", col, value, c)
+		}
+	}
+}
+
+func TestGrayscaleEncoderTransparency(t *testing.T) {
+	tests := []struct {
+		encoder   PNGEncoder
+		values    interface{}
+		mask      []uint8
+		colorType uint8
+		expected  []color.NRGBA64
+	}{
+		// transparent gray value
+		{NewGrayscaleEncoder(3, 1, nil), []uint8{10, 20, 30}, []uint8{255, 0, 255}, pngColorGray,
+			[]color.NRGBA64{{0x0a0a, 0x0a0a, 0x0a0a, 0xffff}, {}, {0x1e1e, 0x1e1e, 0x1e1e, 0xffff}}},
+		// nodata
+		{NewGrayscaleEncoder(3, 1, uint8(20)), []uint8{10, 20, 30}, nil, pngColorGray,
+			[]color.NRGBA64{{0x0a0a, 0x0a0a, 0x0a0a, 0xffff}, {}, {0x1e1e, 0x1e1e, 0x1e1e, 0xffff}}},
+		// partial transparency
+		{NewGrayscaleEncoder(3, 1, nil), []uint8{10, 20, 30}, []uint8{255, 0, 51}, pngColorGrayAlpha,
+			[]color.NRGBA64{{0x0a0a, 0x0a0a, 0x0a0a, 0xffff}, {}, {0x1e1e, 0x1e1e, 0x1e1e, 0x3333}}},
+		{NewGrayscale16Encoder(3, 1, nil), []uint16{1000, 2000, 3000}, []uint8{255, 0, 255}, pngColorGray,
+			[]color.NRGBA64{{1000, 1000, 1000, 0xffff}, {}, {3000, 3000, 3000, 0xffff}}},
+		{NewGrayscale16Encoder(3, 1, int16(0)), []int16{-1000, 0, 1000}, []uint8{255, 255, 51}, pngColorGrayAlpha,
+			[]color.NRGBA64{{31768, 31768, 31768, 0xffff}, {}, {33768, 33768, 33768, 0x3333}}},
+	}
+
+	for i, test := range tests {
+		data, err := test.encoder.Encode(test.values, test.mask)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// color type is the 10th byte of IHDR, after signature and chunk header
+		if colorType := data[25]; colorType != test.colorType {
+			t.Errorf("test %v: color type %v does not match expected value %v", i, colorType, test.colorType)
+		}
+
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for col, expected := range test.expected {
+			value := color.NRGBA64Model.Convert(img.At(col, 0)).(color.NRGBA64)
+			if value.A == 0 {
+				value = color.NRGBA64{}
+			}
+			if value != expected {
+				t.Errorf("test %v: pixel %v: %v does not match expected value %v", i, col, value, expected)
+			}
+		}
+	}
+}
+
+func TestGrayscaleEncoderAllValues(t *testing.T) {
+	// no gray value is available to mark transparent pixels
+	values := make([]uint8, 257)
+	mask := make([]uint8, 257)
+	for i := 0; i < 256; i++ {
+		values[i] = uint8(i)
+		mask[i] = 255
+	}
+
+	data, err := NewGrayscaleEncoder(257, 1, nil).Encode(values, mask)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if colorType := data[25]; colorType != pngColorGrayAlpha {
+		t.Errorf("color type %v does not match expected value %v", colorType, pngColorGrayAlpha)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, col := range []int{0, 128, 255, 256} {
+		expected := color.NRGBA{uint8(col), uint8(col), uint8(col), 255}
+		if col == 256 {
+			expected = color.NRGBA{}
+		}
+		if value := color.NRGBAModel.Convert(img.At(col, 0)).(color.NRGBA); value != expected {
+			t.Errorf("pixel %v: %v does not match expected value %v", col, value, expected)
+		}
+	}
+}
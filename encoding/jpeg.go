@@ -0,0 +1,50 @@
+package encoding
+
+import (
+	"bytes"
+	"fmt"
+	"image/jpeg"
+)
+
+// JPEGEncoder encodes images rendered by a Renderer to JPEG.  JPEG does not
+// support transparency; transparent pixels are encoded as black.
+type JPEGEncoder struct {
+	renderer Renderer
+	quality  int
+	buffer   bytes.Buffer
+}
+
+// Create a JPEGEncoder for images rendered by renderer, with quality (1-100)
+func NewJPEGEncoder(renderer Renderer, quality int) (*JPEGEncoder, error) {
+	if quality < 1 || quality > 100 {
+		return nil, fmt.Errorf("quality must be between 1 and 100: %v", quality)
+	}
+
+	return &JPEGEncoder{
+		renderer: renderer,
+		quality:  quality,
+	}, nil
+}
+
+// Encode buffer to JPEG
+func (e *JPEGEncoder) Encode(buffer interface{}, mask []uint8) ([]byte, error) {
+	img, err := e.renderer.Render(buffer, mask)
+	if err != nil {
+		return nil, err
+	}
+
+	e.buffer.Reset()
+	err = jpeg.Encode(&e.buffer, img, &jpeg.Options{Quality: e.quality})
+	if err != nil {
+		return nil, err
+	}
+	return e.buffer.Bytes(), nil
+}
+
+func (e *JPEGEncoder) Format() string {
+	return "jpg"
+}
+
+func (e *JPEGEncoder) ContentType() string {
+	return "image/jpeg"
+}
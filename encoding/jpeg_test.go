@@ -0,0 +1,47 @@
+package encoding
+
+import (
+	"bytes"
+	"image/jpeg"
+	"testing"
+)
+
+func TestJPEGEncoder(t *testing.T) {
+	encoder, err := NewJPEGEncoder(NewGrayscaleEncoder(16, 16, nil), 90)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := make([]uint8, 16*16)
+	for i := range values {
+		values[i] = 200
+	}
+	data, err := encoder.Encode(values, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := img.Bounds().Size(); size.X != 16 || size.Y != 16 {
+		t.Errorf("decoded image size %v does not match expected size 16x16", size)
+	}
+	r, _, _, _ := img.At(8, 8).RGBA()
+	if value := r >> 8; value < 195 || value > 205 {
+		t.Errorf("pixel %v is not close to expected value 200", value)
+	}
+
+	if encoder.Format() != "jpg" {
+		t.Errorf("format %v does not match expected value jpg", encoder.Format())
+	}
+}
+
+func TestJPEGEncoderQuality(t *testing.T) {
+	for _, quality := range []int{0, 101} {
+		if _, err := NewJPEGEncoder(NewGrayscaleEncoder(1, 1, nil), quality); err == nil {
+			t.Errorf("quality %v did not raise error", quality)
+		}
+	}
+}
@@ -1,6 +1,115 @@
 package encoding
 
-// PNGEncoder provides an Encode() function for encoding buffer to PNG
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+)
+
+// TileEncoder provides an Encode() function for encoding buffer to an image
+// format.  Mask is optional; see Renderer.
+type TileEncoder interface {
+	Encode(buffer interface{}, mask []uint8) ([]byte, error)
+	// Format of encoded tiles as stored in tileset metadata, e.g., "png"
+	Format() string
+	// MIME type of encoded tiles, e.g., "image/png"
+	ContentType() string
+}
+
+// Renderer provides a Render() function for rendering buffer to an image,
+// which may be reused between calls.  Mask is optional; if provided, it must
+// have a value for each pixel from 0 (transparent) to 255 (opaque).
+type Renderer interface {
+	Render(buffer interface{}, mask []uint8) (image.Image, error)
+}
+
+// PNGEncoder encodes buffer to PNG; the rendered image can also be encoded
+// to other formats
 type PNGEncoder interface {
-	Encode(buffer interface{}) ([]byte, error)
+	TileEncoder
+	Renderer
+}
+
+// pngEncoder provides the format of PNG encoders, and reuses a buffer to
+// encode images to PNG
+type pngEncoder struct {
+	pngBuffer bytes.Buffer
+}
+
+func (e *pngEncoder) Format() string {
+	return "png"
+}
+
+func (e *pngEncoder) ContentType() string {
+	return "image/png"
+}
+
+// Return true if mask has any pixels that are not opaque
+func hasTransparency(mask []uint8) bool {
+	for _, value := range mask {
+		if value != 255 {
+			return true
+		}
+	}
+	return false
+}
+
+// Scale alpha by the value of mask at i, if mask is provided
+func maskAlpha(alpha uint8, mask []uint8, i int) uint8 {
+	if mask == nil {
+		return alpha
+	}
+	return uint8(uint16(alpha) * uint16(mask[i]) / 255)
+}
+
+func (e *pngEncoder) encode(img image.Image) ([]byte, error) {
+	e.pngBuffer.Reset()
+	err := png.Encode(&e.pngBuffer, img)
+	if err != nil {
+		return nil, err
+	}
+	return e.pngBuffer.Bytes(), nil
+}
+
+// Create the default PNGEncoder for dtype and number of bands.  Colormap is
+// optional, and is only used for single-band data; categorical colormaps are
+// only supported for integer data.  Nodata is optional, and is only used for
+// grayscale data and gradient colormaps; nodata of multi-band data is
+// applied through the mask.
+func NewEncoder(dtype string, bands int, width int, height int, colormap *Colormap, nodata interface{}) (PNGEncoder, error) {
+	if bands > 1 {
+		if dtype != "uint8" {
+			return nil, fmt.Errorf("encoding not yet supported for multi-band data of dtype: %v", dtype)
+		}
+		return NewRGBAEncoder(width, height, bands)
+	}
+
+	if colormap != nil && colormap.Gradient() != nil {
+		return NewGradientEncoder(width, height, colormap.Gradient(), dtype, nodata), nil
+	}
+	if colormap != nil {
+		switch dtype {
+		case "uint8":
+			if len(colormap.Palette()) <= 256 {
+				return NewColormapEncoder(width, height, colormap)
+			}
+			return NewColormap16Encoder(width, height, colormap, dtype), nil
+		case "int8", "int16", "uint16", "int32", "uint32":
+			return NewColormap16Encoder(width, height, colormap, dtype), nil
+		default:
+			return nil, fmt.Errorf("categorical colormap not supported for dtype: %v", dtype)
+		}
+	}
+
+	switch dtype {
+	case "int8", "uint8":
+		return NewGrayscaleEncoder(width, height, nodata), nil
+	case "int16", "uint16":
+		return NewGrayscale16Encoder(width, height, nodata), nil
+	case "int32", "uint32":
+		return NewRGBEncoder(width, height), nil
+	default:
+		return nil, fmt.Errorf("encoding not yet supported for other dtypes: %v", dtype)
+	}
 }
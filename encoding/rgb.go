@@ -1,16 +1,22 @@
 package encoding
 
 import (
-	"bytes"
+	"fmt"
 	"image"
-	"image/png"
 )
 
+// range of int32 values that can be packed into 24 bits
+const minInt24 = -1 << 23
+const maxInt24 = 1<<23 - 1
+
+// maximum uint32 value that can be packed into 24 bits
+const maxUint24 = 1<<24 - 1
+
 type RGBEncoder struct {
-	img       *image.NRGBA // TODO:
-	pngBuffer bytes.Buffer
-	width     int
-	height    int
+	pngEncoder
+	img    *image.NRGBA // TODO:
+	width  int
+	height int
 }
 
 func NewRGBEncoder(width int, height int) *RGBEncoder {
@@ -21,29 +27,55 @@ func NewRGBEncoder(width int, height int) *RGBEncoder {
 	}
 }
 
-// Encode uint8...uint32 values to 24-bit RGB PNG
-func (e *RGBEncoder) Encode(buffer interface{}) ([]byte, error) {
+// Render uint8...uint32 values to 24-bit RGB image, using mask for alpha if
+// provided.  int32 values are offset by 2^23 so that values from -2^23 to
+// 2^23-1 are packed in order.  Returns an error if a valid value cannot be
+// packed into 24 bits; values of pixels outside the mask are ignored.
+func (e *RGBEncoder) Render(buffer interface{}, mask []uint8) (image.Image, error) {
 	switch typedBuffer := buffer.(type) {
+	case []int32:
+		var value uint32
+		for row := 0; row < e.height; row++ {
+			for col := 0; col < e.width; col++ {
+				raw := typedBuffer[row*e.width+col]
+				if (raw < minInt24 || raw > maxInt24) && (mask == nil || mask[row*e.width+col] != 0) {
+					return nil, fmt.Errorf("int32 value %v is outside the range that can be encoded without a colormap or rescale: %v to %v", raw, minInt24, maxInt24)
+				}
+				value = uint32(raw) + 1<<23
+				i := e.img.PixOffset(col, row)
+				e.img.Pix[i] = uint8(value>>16) & 255  // R
+				e.img.Pix[i+1] = uint8(value>>8) & 255 // G
+				e.img.Pix[i+2] = uint8(value) & 255    // B
+				e.img.Pix[i+3] = maskAlpha(255, mask, row*e.width+col)
+			}
+		}
 	case []uint32:
 		var value uint32
 		for row := 0; row < e.height; row++ {
 			for col := 0; col < e.width; col++ {
 				value = typedBuffer[row*e.width+col]
+				if value > maxUint24 && (mask == nil || mask[row*e.width+col] != 0) {
+					return nil, fmt.Errorf("uint32 value %v is outside the range that can be encoded without a colormap or rescale: 0 to %v", value, maxUint24)
+				}
 				i := e.img.PixOffset(col, row)
 				e.img.Pix[i] = uint8(value>>16) & 255  // R
 				e.img.Pix[i+1] = uint8(value>>8) & 255 // G
 				e.img.Pix[i+2] = uint8(value) & 255    // B
-				e.img.Pix[i+3] = 255                   // A, hardcoded for RGB
+				e.img.Pix[i+3] = maskAlpha(255, mask, row*e.width+col)
 			}
 		}
 	default:
 		panic("Other dtypes not yet supported for RGBEncoder::Encode()")
 	}
 
-	e.pngBuffer.Reset()
-	err := png.Encode(&e.pngBuffer, e.img)
+	return e.img, nil
+}
+
+// Encode buffer to PNG
+func (e *RGBEncoder) Encode(buffer interface{}, mask []uint8) ([]byte, error) {
+	img, err := e.Render(buffer, mask)
 	if err != nil {
 		return nil, err
 	}
-	return e.pngBuffer.Bytes(), nil
+	return e.encode(img)
 }
@@ -0,0 +1,47 @@
+package encoding
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestRGBEncoder(t *testing.T) {
+	encoder := NewRGBEncoder(3, 1)
+
+	img, err := encoder.Render([]int32{-1 << 23, 0, 1<<23 - 1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []color.NRGBA{{0, 0, 0, 255}, {128, 0, 0, 255}, {255, 255, 255, 255}}
+	for col, value := range expected {
+		if pixel := img.(*image.NRGBA).NRGBAAt(col, 0); pixel != value {
+			t.Errorf("int32 pixel %v: %v does not match expected value %v", col, pixel, value)
+		}
+	}
+
+	img, err = encoder.Render([]uint32{0, 256, 1<<24 - 1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []color.NRGBA{{0, 0, 0, 255}, {0, 1, 0, 255}, {255, 255, 255, 255}}
+	for col, value := range expected {
+		if pixel := img.(*image.NRGBA).NRGBAAt(col, 0); pixel != value {
+			t.Errorf("uint32 pixel %v: %v does not match expected value %v", col, pixel, value)
+		}
+	}
+
+	// values outside 24 bits are not valid unless masked
+	if _, err = encoder.Render([]int32{1 << 23, 0, 0}, nil); err == nil {
+		t.Errorf("Render() did not return error for int32 value above range")
+	}
+	if _, err = encoder.Render([]int32{0, -1<<23 - 1, 0}, nil); err == nil {
+		t.Errorf("Render() did not return error for int32 value below range")
+	}
+	if _, err = encoder.Render([]uint32{0, 0, 1 << 24}, nil); err == nil {
+		t.Errorf("Render() did not return error for uint32 value above range")
+	}
+	if _, err = encoder.Render([]int32{-1 << 31, 0, 0}, []uint8{0, 255, 255}); err != nil {
+		t.Errorf("Render() returned error for masked value: %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package encoding
+
+import (
+	"fmt"
+	"image"
+)
+
+// RGBAEncoder encodes 3-band (RGB) or 4-band (RGBA) data where each band is
+// stored sequentially in the buffer, as read from a multi-band dataset
+type RGBAEncoder struct {
+	pngEncoder
+	img    *image.NRGBA
+	width  int
+	height int
+	bands  int
+}
+
+// Create an RGBAEncoder for 3 or 4 bands
+func NewRGBAEncoder(width int, height int, bands int) (*RGBAEncoder, error) {
+	if bands != 3 && bands != 4 {
+		return nil, fmt.Errorf("RGBAEncoder requires 3 or 4 bands, got %v", bands)
+	}
+
+	return &RGBAEncoder{
+		img:    image.NewNRGBA(image.Rect(0, 0, width, height)),
+		width:  width,
+		height: height,
+		bands:  bands,
+	}, nil
+}
+
+// Render uint8 bands to 32-bit RGBA image.  Alpha of 4-band data is the
+// alpha band, which GDAL also uses as the mask, so mask is only used for
+// 3-band data; pixels of 3-band data with nodata are masked where all bands
+// equal nodata (see gdal.Dataset.ReadMask()).
+func (e *RGBAEncoder) Render(buffer interface{}, mask []uint8) (image.Image, error) {
+	switch typedBuffer := buffer.(type) {
+	case []uint8:
+		bandSize := e.width * e.height
+		if len(typedBuffer) != bandSize*e.bands {
+			return nil, fmt.Errorf("buffer is not expected size for %v bands: %v", e.bands, len(typedBuffer))
+		}
+
+		var r, g, b, a uint8
+		for row := 0; row < e.height; row++ {
+			for col := 0; col < e.width; col++ {
+				offset := row*e.width + col
+				r = typedBuffer[offset]
+				g = typedBuffer[bandSize+offset]
+				b = typedBuffer[2*bandSize+offset]
+				if e.bands == 4 {
+					a = typedBuffer[3*bandSize+offset]
+				} else {
+					a = maskAlpha(255, mask, offset)
+				}
+
+				i := e.img.PixOffset(col, row)
+				e.img.Pix[i] = r
+				e.img.Pix[i+1] = g
+				e.img.Pix[i+2] = b
+				e.img.Pix[i+3] = a
+			}
+		}
+	default:
+		panic("Other dtypes not yet supported for RGBAEncoder::Encode()")
+	}
+
+	return e.img, nil
+}
+
+// Encode buffer to PNG
+func (e *RGBAEncoder) Encode(buffer interface{}, mask []uint8) ([]byte, error) {
+	img, err := e.Render(buffer, mask)
+	if err != nil {
+		return nil, err
+	}
+	return e.encode(img)
+}
@@ -0,0 +1,95 @@
+package encoding
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func decodeNRGBA(t *testing.T, data []byte) *image.NRGBA {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nrgba, ok := img.(*image.NRGBA)
+	if !ok {
+		t.Fatalf("decoded image is not NRGBA: %T", img)
+	}
+	return nrgba
+}
+
+func TestRGBAEncoder(t *testing.T) {
+	// 3x1 pixels, 3 bands stored sequentially; only the mask makes pixels
+	// transparent, so a single band that equals nodata remains opaque
+	encoder, err := NewRGBAEncoder(3, 1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := encoder.Encode([]uint8{10, 0, 0, 20, 255, 0, 30, 0, 0}, []uint8{255, 255, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := decodeNRGBA(t, data)
+	expected := []color.NRGBA{{10, 20, 30, 255}, {0, 255, 0, 255}, {0, 0, 0, 0}}
+	for col, value := range expected {
+		if img.NRGBAAt(col, 0) != value {
+			t.Errorf("pixel %v: %v does not match expected value %v", col, img.NRGBAAt(col, 0), value)
+		}
+	}
+
+	// 4 bands, alpha from last band
+	encoder, err = NewRGBAEncoder(2, 1, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = encoder.Encode([]uint8{10, 40, 20, 50, 30, 60, 255, 128}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img = decodeNRGBA(t, data)
+	expected = []color.NRGBA{{10, 20, 30, 255}, {40, 50, 60, 128}}
+	for col, value := range expected {
+		if img.NRGBAAt(col, 0) != value {
+			t.Errorf("pixel %v: %v does not match expected value %v", col, img.NRGBAAt(col, 0), value)
+		}
+	}
+
+	// alpha band is also the mask of 4-band data, so it is not applied twice
+	data, err = encoder.Encode([]uint8{10, 40, 20, 50, 30, 60, 255, 128}, []uint8{255, 128})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img = decodeNRGBA(t, data)
+	for col, value := range expected {
+		if img.NRGBAAt(col, 0) != value {
+			t.Errorf("masked pixel %v: %v does not match expected value %v", col, img.NRGBAAt(col, 0), value)
+		}
+	}
+
+	// mask is used for alpha of 3-band data
+	encoder3, err := NewRGBAEncoder(2, 1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = encoder3.Encode([]uint8{10, 40, 20, 50, 30, 60}, []uint8{255, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img = decodeNRGBA(t, data)
+	expected = []color.NRGBA{{10, 20, 30, 255}, {40, 50, 60, 0}}
+	for col, value := range expected {
+		if img.NRGBAAt(col, 0) != value {
+			t.Errorf("3-band masked pixel %v: %v does not match expected value %v", col, img.NRGBAAt(col, 0), value)
+		}
+	}
+
+	if _, err = encoder.Encode([]uint8{1, 2, 3}, nil); err == nil {
+		t.Errorf("Encode() did not return error for buffer of wrong size")
+	}
+
+	if _, err = NewRGBAEncoder(2, 1, 2); err == nil {
+		t.Errorf("NewRGBAEncoder() did not return error for 2 bands")
+	}
+}
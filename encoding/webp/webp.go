@@ -0,0 +1,49 @@
+package webp
+
+import (
+	"fmt"
+
+	"github.com/brendan-ward/rastertiler/encoding"
+	"github.com/brendan-ward/rastertiler/gdal"
+)
+
+// WebPEncoder encodes images rendered by a Renderer to WebP, using the GDAL
+// WEBP driver
+type WebPEncoder struct {
+	renderer encoding.Renderer
+	options  []string
+}
+
+// Create a WebPEncoder for images rendered by renderer.  If lossless is
+// false, quality (1-100) sets the quality of lossy compression.
+func NewWebPEncoder(renderer encoding.Renderer, lossless bool, quality int) (*WebPEncoder, error) {
+	if quality < 1 || quality > 100 {
+		return nil, fmt.Errorf("quality must be between 1 and 100: %v", quality)
+	}
+
+	options := []string{fmt.Sprintf("QUALITY=%v", quality)}
+	if lossless {
+		options = []string{"LOSSLESS=YES"}
+	}
+
+	return &WebPEncoder{
+		renderer: renderer,
+		options:  options,
+	}, nil
+}
+
+func (e *WebPEncoder) Encode(buffer interface{}, mask []uint8) ([]byte, error) {
+	img, err := e.renderer.Render(buffer, mask)
+	if err != nil {
+		return nil, err
+	}
+	return gdal.EncodeImage(img, "WEBP", e.options)
+}
+
+func (e *WebPEncoder) Format() string {
+	return "webp"
+}
+
+func (e *WebPEncoder) ContentType() string {
+	return "image/webp"
+}
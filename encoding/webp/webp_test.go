@@ -0,0 +1,33 @@
+package webp
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/brendan-ward/rastertiler/encoding"
+)
+
+func TestWebPEncoder(t *testing.T) {
+	buffer := make([]uint8, 16*16)
+	for i := range buffer {
+		buffer[i] = uint8(i)
+	}
+
+	for _, lossless := range []bool{true, false} {
+		encoder, err := NewWebPEncoder(encoding.NewGrayscaleEncoder(16, 16, nil), lossless, 80)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := encoder.Encode(buffer, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) < 12 || !bytes.Equal(data[0:4], []byte("RIFF")) || !bytes.Equal(data[8:12], []byte("WEBP")) {
+			t.Errorf("encoded data is not WebP (lossless: %v)", lossless)
+		}
+	}
+
+	if _, err := NewWebPEncoder(encoding.NewGrayscaleEncoder(16, 16, nil), false, 0); err == nil {
+		t.Errorf("NewWebPEncoder() did not return error for invalid quality")
+	}
+}
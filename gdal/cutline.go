@@ -0,0 +1,178 @@
+package gdal
+
+// #include "gdal.h"
+// #include "ogr_api.h"
+import "C"
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/brendan-ward/rastertiler/affine"
+)
+
+// Cutline is a polygon used to clip a dataset when warping
+type Cutline struct {
+	geometry C.OGRGeometryH // with spatial reference assigned
+}
+
+// Read a cutline from the union of all polygons of all layers of a vector
+// dataset that can be read by OGR, e.g., GeoJSON
+func ReadCutline(filename string) (*Cutline, error) {
+	cFilename := C.CString(filename)
+	defer C.free(unsafe.Pointer(cFilename))
+
+	ds := C.GDALOpenEx(cFilename, C.GDAL_OF_VECTOR|C.GDAL_OF_READONLY, nil, nil, nil)
+	if ds == nil {
+		return nil, fmt.Errorf("could not open cutline: %v", filename)
+	}
+	defer C.GDALClose(ds)
+
+	c := &Cutline{}
+	var srs C.OGRSpatialReferenceH
+	for i := 0; i < int(C.GDALDatasetGetLayerCount(ds)); i++ {
+		layer := C.GDALDatasetGetLayer(ds, C.int(i))
+		C.OGR_L_ResetReading(layer)
+		for feature := C.OGR_L_GetNextFeature(layer); feature != nil; feature = C.OGR_L_GetNextFeature(layer) {
+			err := c.add(C.OGR_F_GetGeometryRef(feature), &srs)
+			C.OGR_F_Destroy(feature)
+			if err != nil {
+				c.Close()
+				return nil, fmt.Errorf("invalid cutline %v: %v", filename, err)
+			}
+		}
+	}
+
+	if c.geometry == nil {
+		return nil, fmt.Errorf("cutline %v does not contain any polygons", filename)
+	}
+	C.OGR_G_AssignSpatialReference(c.geometry, srs)
+	return c, nil
+}
+
+// Add polygon geometry to the union of polygons of the cutline, in the
+// spatial reference of the first geometry, which is set to srs
+func (c *Cutline) add(geometry C.OGRGeometryH, srs *C.OGRSpatialReferenceH) error {
+	if geometry == nil || C.OGR_G_IsEmpty(geometry) != 0 {
+		return nil
+	}
+	geometryType := C.OGR_GT_Flatten(C.OGR_G_GetGeometryType(geometry))
+	if geometryType != C.wkbPolygon && geometryType != C.wkbMultiPolygon {
+		return fmt.Errorf("cutline must only contain polygons")
+	}
+	geometrySRS := C.OGR_G_GetSpatialReference(geometry)
+	if geometrySRS == nil {
+		return fmt.Errorf("cutline must have a CRS")
+	}
+
+	if c.geometry == nil {
+		*srs = geometrySRS
+		c.geometry = C.OGR_G_Clone(geometry)
+		return nil
+	}
+
+	geometry = C.OGR_G_Clone(geometry)
+	defer C.OGR_G_DestroyGeometry(geometry)
+
+	if C.OSRIsSame(geometrySRS, *srs) == 0 && C.OGR_G_TransformTo(geometry, *srs) != C.OGRERR_NONE {
+		return fmt.Errorf("could not transform polygon to CRS of cutline")
+	}
+	union := C.OGR_G_Union(c.geometry, geometry)
+	if union == nil {
+		return fmt.Errorf("could not combine polygons")
+	}
+	C.OGR_G_DestroyGeometry(c.geometry)
+	c.geometry = union
+	return nil
+}
+
+func (c *Cutline) Close() {
+	if c == nil {
+		return
+	}
+	if c.geometry != nil {
+		C.OGR_G_DestroyGeometry(c.geometry)
+	}
+	*c = Cutline{}
+}
+
+// Create a copy of the cutline projected to crs, e.g., "EPSG:3857"
+func (c *Cutline) Transform(crs string) (*Cutline, error) {
+	srs, err := newSpatialReference(crs)
+	if err != nil {
+		return nil, err
+	}
+	defer C.OSRDestroySpatialReference(srs)
+
+	return c.transformTo(srs)
+}
+
+func (c *Cutline) transformTo(srs C.OGRSpatialReferenceH) (*Cutline, error) {
+	geometry := C.OGR_G_Clone(c.geometry)
+	if C.OGR_G_TransformTo(geometry, srs) != C.OGRERR_NONE {
+		C.OGR_G_DestroyGeometry(geometry)
+		return nil, fmt.Errorf("could not transform cutline")
+	}
+	return &Cutline{geometry: geometry}, nil
+}
+
+// Get bounds of cutline in its CRS
+func (c *Cutline) Bounds() *affine.Bounds {
+	var envelope C.OGREnvelope
+	C.OGR_G_GetEnvelope(c.geometry, &envelope)
+	return &affine.Bounds{Xmin: float64(envelope.MinX), Ymin: float64(envelope.MinY), Xmax: float64(envelope.MaxX), Ymax: float64(envelope.MaxY)}
+}
+
+// Intersects returns true if the cutline intersects bounds, which are in the
+// CRS of the cutline.  Safe to call from multiple goroutines.
+func (c *Cutline) Intersects(bounds *affine.Bounds) bool {
+	wkt := C.CString(fmt.Sprintf("POLYGON ((%v %v, %v %v, %v %v, %v %v, %v %v))",
+		bounds.Xmin, bounds.Ymin, bounds.Xmax, bounds.Ymin, bounds.Xmax, bounds.Ymax, bounds.Xmin, bounds.Ymax, bounds.Xmin, bounds.Ymin))
+	defer C.free(unsafe.Pointer(wkt))
+
+	// OGR_G_CreateFromWkt advances the pointer it is passed
+	wktPtr := wkt
+	var box C.OGRGeometryH
+	if C.OGR_G_CreateFromWkt(&wktPtr, nil, &box) != C.OGRERR_NONE {
+		return false
+	}
+	defer C.OGR_G_DestroyGeometry(box)
+
+	return C.OGR_G_Intersects(c.geometry, box) != 0
+}
+
+// Get the cutline as WKT in the pixel coordinates of dataset d, as used for
+// the CUTLINE warp option
+func (c *Cutline) pixelWKT(d *Dataset) (string, error) {
+	srs := C.GDALGetSpatialRef(d.ptr)
+	if srs == nil {
+		return "", fmt.Errorf("dataset must have a CRS to use a cutline")
+	}
+	pixels, err := c.transformTo(srs)
+	if err != nil {
+		return "", err
+	}
+	defer pixels.Close()
+
+	C.OGR_G_FlattenTo2D(pixels.geometry)
+	transformPoints(pixels.geometry, d.transform.Invert())
+
+	var wkt *C.char
+	if C.OGR_G_ExportToWkt(pixels.geometry, &wkt) != C.OGRERR_NONE {
+		return "", fmt.Errorf("could not export cutline to WKT")
+	}
+	defer C.CPLFree(unsafe.Pointer(wkt))
+
+	return C.GoString(wkt), nil
+}
+
+// Apply transform to all points of geometry and its parts, such as polygon
+// rings
+func transformPoints(geometry C.OGRGeometryH, transform *affine.Affine) {
+	for i := 0; i < int(C.OGR_G_GetGeometryCount(geometry)); i++ {
+		transformPoints(C.OGR_G_GetGeometryRef(geometry, C.int(i)), transform)
+	}
+	for i := 0; i < int(C.OGR_G_GetPointCount(geometry)); i++ {
+		x, y := transform.Multiply(float64(C.OGR_G_GetX(geometry, C.int(i))), float64(C.OGR_G_GetY(geometry, C.int(i))))
+		C.OGR_G_SetPoint_2D(geometry, C.int(i), C.double(x), C.double(y))
+	}
+}
@@ -0,0 +1,97 @@
+package gdal
+
+// #include "gdal.h"
+// #include "cpl_vsi.h"
+import "C"
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"sync/atomic"
+	"unsafe"
+)
+
+// counter used to create unique in-memory filenames
+var vsimemCounter uint64
+
+// Encode image to an image format using a GDAL driver that supports
+// CreateCopy, e.g., "WEBP", with driver creation options.  Image is encoded
+// as 4-band RGBA.
+func EncodeImage(img image.Image, driver string, options []string) ([]byte, error) {
+	nrgba, ok := img.(*image.NRGBA)
+	if !ok {
+		bounds := img.Bounds()
+		nrgba = image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		draw.Draw(nrgba, nrgba.Bounds(), img, bounds.Min, draw.Src)
+	}
+	width := nrgba.Rect.Dx()
+	height := nrgba.Rect.Dy()
+
+	memDriverName := C.CString("MEM")
+	defer C.free(unsafe.Pointer(memDriverName))
+	emptyName := C.CString("")
+	defer C.free(unsafe.Pointer(emptyName))
+
+	memDs := C.GDALCreate(C.GDALGetDriverByName(memDriverName), emptyName, C.int(width), C.int(height), 4, C.GDT_Byte, nil)
+	if unsafe.Pointer(memDs) == nil {
+		return nil, fmt.Errorf("could not create in-memory dataset")
+	}
+	defer C.GDALClose(memDs)
+
+	// write pixel-interleaved RGBA values to bands
+	if C.GDALDatasetRasterIO(
+		memDs,
+		C.GF_Write,
+		0,
+		0,
+		C.int(width),
+		C.int(height),
+		unsafe.Pointer(&nrgba.Pix[0]),
+		C.int(width),
+		C.int(height),
+		C.GDT_Byte,
+		4,                   // number of bands being written
+		nil,                 // default to selecting first 4 bands for writing
+		4,                   // pixel spacing
+		C.int(nrgba.Stride), // line spacing
+		1,                   // band spacing
+	) != C.CE_None {
+		return nil, fmt.Errorf("could not write data to in-memory dataset")
+	}
+
+	cDriverName := C.CString(driver)
+	defer C.free(unsafe.Pointer(cDriverName))
+	outDriver := C.GDALGetDriverByName(cDriverName)
+	if unsafe.Pointer(outDriver) == nil {
+		return nil, fmt.Errorf("GDAL driver not available: %v", driver)
+	}
+
+	// create a null-terminated C string array
+	length := len(options)
+	gdalOpts := make([]*C.char, length+1)
+	for i := 0; i < len(options); i++ {
+		gdalOpts[i] = C.CString(options[i])
+		defer C.free(unsafe.Pointer(gdalOpts[i]))
+	}
+	gdalOpts[length] = (*C.char)(unsafe.Pointer(nil))
+
+	filename := C.CString(fmt.Sprintf("/vsimem/rastertiler_%v", atomic.AddUint64(&vsimemCounter, 1)))
+	defer C.free(unsafe.Pointer(filename))
+
+	outDs := C.GDALCreateCopy(outDriver, filename, memDs, 0, (**C.char)(unsafe.Pointer(&gdalOpts[0])), nil, nil)
+	if unsafe.Pointer(outDs) == nil {
+		C.VSIUnlink(filename)
+		return nil, fmt.Errorf("could not encode image using %v driver: %v", driver, C.GoString(C.CPLGetLastErrorMsg()))
+	}
+	C.GDALClose(outDs)
+
+	// take ownership of in-memory file buffer, which removes the file
+	var size C.vsi_l_offset
+	data := C.VSIGetMemFileBuffer(filename, &size, 1)
+	if unsafe.Pointer(data) == nil {
+		return nil, fmt.Errorf("could not read encoded image")
+	}
+	defer C.VSIFree(unsafe.Pointer(data))
+
+	return C.GoBytes(unsafe.Pointer(data), C.int(size)), nil
+}
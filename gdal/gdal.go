@@ -4,9 +4,17 @@ package gdal
 // #include "gdal.h"
 // #include "gdalwarper.h"
 // #include "ogr_srs_api.h"
+//
+// // GDT_Int8 is only available in GDAL >= 3.7
+// #if GDAL_VERSION_NUM >= GDAL_COMPUTE_VERSION(3, 7, 0)
+// #define GDT_INT8_IF_AVAILABLE GDT_Int8
+// #else
+// #define GDT_INT8_IF_AVAILABLE -1
+// #endif
 import "C"
 import (
 	"fmt"
+	"image/color"
 	"math"
 	"unsafe"
 
@@ -17,25 +25,28 @@ import (
 
 const Version string = C.GDAL_RELEASE_NAME
 
-const RESAMPLING_NEAREST int = 0
-
 // mapping of GDAL
 var gdalDtypeStr = map[int]string{
-	C.GDT_Byte:   "uint8",
-	C.GDT_UInt16: "uint16",
-	C.GDT_Int16:  "int16",
-	C.GDT_UInt32: "uint32",
-	C.GDT_Int32:  "int32",
+	C.GDT_INT8_IF_AVAILABLE: "int8",
+	C.GDT_Byte:              "uint8",
+	C.GDT_UInt16:            "uint16",
+	C.GDT_Int16:             "int16",
+	C.GDT_UInt32:            "uint32",
+	C.GDT_Int32:             "int32",
+	C.GDT_Float32:           "float32",
+	C.GDT_Float64:           "float64",
 }
 
 var gdalDtype = map[string]int{
-	"byte":   C.GDT_Byte,
-	"uint8":  C.GDT_Byte,
-	"uint16": C.GDT_UInt16,
-	"uint32": C.GDT_UInt32,
-	"int8":   C.GDT_Byte, // Note: requires setting an option when creating dataset
-	"int16":  C.GDT_Int16,
-	"int32":  C.GDT_Int32,
+	"byte":    C.GDT_Byte,
+	"uint8":   C.GDT_Byte,
+	"uint16":  C.GDT_UInt16,
+	"uint32":  C.GDT_UInt32,
+	"int8":    C.GDT_Byte, // Note: requires setting an option when creating dataset
+	"int16":   C.GDT_Int16,
+	"int32":   C.GDT_Int32,
+	"float32": C.GDT_Float32,
+	"float64": C.GDT_Float64,
 }
 
 func init() {
@@ -46,17 +57,41 @@ type Dataset struct {
 	path      string
 	ptr       C.GDALDatasetH
 	driver    string
-	dtype     string
+	dtype     string   // dtype of first band, used for reading all bands
+	dtypes    []string // dtype of each band
+	bandCount int
+	maskFlags int // GMF_* flags of the mask of the first band
 	crs       string
 	transform *affine.Affine
 	width     int
 	height    int
 	nodata    interface{} // value is in dtype
 	bounds    *affine.Bounds
+	source    *Dataset // dataset read by a VRT, closed with the VRT
+}
+
+// Get the dtype of band, or "" if it is not supported; int8 data are stored
+// as bytes with PIXELTYPE=SIGNEDBYTE before GDAL 3.7
+func bandDtype(band C.GDALRasterBandH) string {
+	dtype := gdalDtypeStr[int(C.GDALGetRasterDataType(band))]
+	if dtype != "uint8" {
+		return dtype
+	}
+
+	key := C.CString("PIXELTYPE")
+	defer C.free(unsafe.Pointer(key))
+	domain := C.CString("IMAGE_STRUCTURE")
+	defer C.free(unsafe.Pointer(domain))
+
+	pixelType := C.GDALGetMetadataItem(C.GDALMajorObjectH(band), key, domain)
+	if pixelType != nil && C.GoString(pixelType) == "SIGNEDBYTE" {
+		return "int8"
+	}
+	return dtype
 }
 
 func newDataset(filename string, ptr C.GDALDatasetH) (*Dataset, error) {
-	// assume 1-band data
+	// nodata is read from the first band, and assumed to be the same for all bands
 	band := C.GDALGetRasterBand(ptr, 1)
 	if unsafe.Pointer(band) == nil {
 		return nil, fmt.Errorf("could not get raster band")
@@ -64,7 +99,17 @@ func newDataset(filename string, ptr C.GDALDatasetH) (*Dataset, error) {
 
 	driver := C.GoString(C.GDALGetDriverShortName(C.GDALGetDatasetDriver(ptr)))
 	crs := C.GoString(C.GDALGetProjectionRef(ptr))
-	dtype := gdalDtypeStr[int(C.GDALGetRasterDataType(C.GDALGetRasterBand(ptr, 1)))]
+
+	bandCount := int(C.GDALGetRasterCount(ptr))
+	dtypes := make([]string, bandCount)
+	for i := 0; i < bandCount; i++ {
+		dtypes[i] = bandDtype(C.GDALGetRasterBand(ptr, C.int(i+1)))
+		if dtypes[i] == "" {
+			dataType := C.GDALGetRasterDataType(C.GDALGetRasterBand(ptr, C.int(i+1)))
+			return nil, fmt.Errorf("data type of band %v is not supported: %v", i+1, C.GoString(C.GDALGetDataTypeName(dataType)))
+		}
+	}
+	dtype := dtypes[0]
 	width := int(C.GDALGetRasterXSize(ptr))
 	height := int(C.GDALGetRasterYSize(ptr))
 
@@ -74,32 +119,19 @@ func newDataset(filename string, ptr C.GDALDatasetH) (*Dataset, error) {
 	}
 	transform := affine.FromGDAL(rawTransform)
 
-	rawNodata := int(C.GDALGetRasterNoDataValue(band, nil))
+	// nodata that cannot be represented by dtype never matches any value, so
+	// it is ignored
+	var hasNodata C.int
+	rawNodata := float64(C.GDALGetRasterNoDataValue(band, &hasNodata))
 	var nodata interface{}
-
-	switch dtype {
-	case "int8":
-		nodata = int8(rawNodata)
-	case "uint8":
-		nodata = uint8(rawNodata)
-	case "int16":
-		nodata = int16(rawNodata)
-	case "uint16":
-		nodata = uint16(rawNodata)
-	case "int32":
-		nodata = int32(rawNodata)
-	case "uint32":
-		nodata = uint32(rawNodata)
-	default:
-		panic("Nodata() not yet supported for other dtypes")
+	if hasNodata != 0 {
+		nodata, _ = array.FromFloat(rawNodata, dtype)
 	}
 
-	if transform.D > 0 {
-		panic("rasters anchored from bottom left not yet supported")
-	}
-
-	// raster is anchored in upper left; this is the standard direction
-	bounds := &affine.Bounds{transform.C, transform.F + transform.E*float64(height), transform.C + transform.A*float64(width), transform.F}
+	// bounds contain all four corners, so that rasters anchored from the
+	// bottom left (south-up) or rotated are fully covered; these are
+	// north-up after warping
+	bounds := transform.TransformBounds(&affine.Bounds{Xmin: 0, Ymin: 0, Xmax: float64(width), Ymax: float64(height)})
 
 	return &Dataset{
 		path:      filename,
@@ -110,6 +142,9 @@ func newDataset(filename string, ptr C.GDALDatasetH) (*Dataset, error) {
 		width:     width,
 		height:    height,
 		dtype:     dtype,
+		dtypes:    dtypes,
+		bandCount: bandCount,
+		maskFlags: int(C.GDALGetMaskFlags(band)),
 		nodata:    nodata,
 		bounds:    bounds,
 	}, nil
@@ -124,13 +159,21 @@ func Open(filename string) (*Dataset, error) {
 		return nil, fmt.Errorf("could not open dataset: %v", filename)
 	}
 
-	return newDataset(filename, ptr)
+	d, err := newDataset(filename, ptr)
+	if err != nil {
+		C.GDALClose(ptr)
+		return nil, err
+	}
+	return d, nil
 }
 
 func (d *Dataset) Close() {
 	if d != nil && unsafe.Pointer(d.ptr) != nil {
 		C.GDALClose(d.ptr)
 	}
+	if d != nil && d.source != nil {
+		d.source.Close()
+	}
 	// clear out previous references
 	*d = Dataset{}
 }
@@ -150,30 +193,57 @@ func (d *Dataset) Bounds() *affine.Bounds {
 
 // Get geographic bounds of dataset
 func (d *Dataset) GeoBounds() (*affine.Bounds, error) {
-	return d.transformBounds("EPSG:4326")
+	return d.TransformBounds("EPSG:4326")
 }
 
 // Get Mercator bounds of dataset
 func (d *Dataset) MercatorBounds() (*affine.Bounds, error) {
-	return d.transformBounds("EPSG:3857")
+	return d.TransformBounds("EPSG:3857")
 }
 
-// Project dataset bounds to CRS
-func (d *Dataset) transformBounds(crs string) (*affine.Bounds, error) {
+// Create a spatial reference from crs, e.g., "EPSG:4326", that uses x
+// (easting or longitude), y (northing or latitude) order; caller must destroy
+// it
+func newSpatialReference(crs string) (C.OGRSpatialReferenceH, error) {
+	cCRS := C.CString(crs)
+	defer C.free(unsafe.Pointer(cCRS))
+
+	srs := C.OSRNewSpatialReference(nil)
+	if C.OSRSetFromUserInput(srs, cCRS) != C.OGRERR_NONE {
+		C.OSRDestroySpatialReference(srs)
+		return nil, fmt.Errorf("could not set SRS to %v", crs)
+	}
+	// make sure that coords are always returned in long/lat order (otherwise EPSG:4326 returns in opposite order)
+	C.OSRSetAxisMappingStrategy(srs, C.OAMS_TRADITIONAL_GIS_ORDER)
+	return srs, nil
+}
+
+// Project dataset bounds to CRS; coordinates are always in x (easting or
+// longitude), y (northing or latitude) order
+func (d *Dataset) TransformBounds(crs string) (*affine.Bounds, error) {
 	d.mustBeOpen()
 
-	srcSRS := C.GDALGetSpatialRef(d.ptr)
+	return transformBounds(C.GDALGetSpatialRef(d.ptr), d.bounds, crs)
+}
 
-	targetSRSName := C.CString(crs)
-	defer C.free(unsafe.Pointer(targetSRSName))
-	targetSRS := C.OSRNewSpatialReference(nil)
-	defer C.OSRDestroySpatialReference(targetSRS)
-	C.OSRSetFromUserInput(targetSRS, targetSRSName)
-	if unsafe.Pointer(targetSRS) == nil {
-		return nil, fmt.Errorf("could not set target SRS to WGS84")
+// Project geographic (longitude, latitude) bounds to CRS
+func TransformGeoBounds(bounds *affine.Bounds, crs string) (*affine.Bounds, error) {
+	srcSRS, err := newSpatialReference("EPSG:4326")
+	if err != nil {
+		return nil, err
 	}
-	// make sure that coords are always returned in long/lat order (otherwise EPSG:4326 returns in opposite order)
-	C.OSRSetAxisMappingStrategy(targetSRS, C.OAMS_TRADITIONAL_GIS_ORDER)
+	defer C.OSRDestroySpatialReference(srcSRS)
+
+	return transformBounds(srcSRS, bounds, crs)
+}
+
+// Project bounds in the CRS of srcSRS to CRS
+func transformBounds(srcSRS C.OGRSpatialReferenceH, srcBounds *affine.Bounds, crs string) (*affine.Bounds, error) {
+	targetSRS, err := newSpatialReference(crs)
+	if err != nil {
+		return nil, err
+	}
+	defer C.OSRDestroySpatialReference(targetSRS)
 
 	transform := C.OCTNewCoordinateTransformation(srcSRS, targetSRS)
 	if unsafe.Pointer(transform) == nil {
@@ -185,17 +255,17 @@ func (d *Dataset) transformBounds(crs string) (*affine.Bounds, error) {
 
 	if C.OCTTransformBounds(
 		transform,
-		C.double(d.bounds.Xmin),
-		C.double(d.bounds.Ymin),
-		C.double(d.bounds.Xmax),
-		C.double(d.bounds.Ymax),
+		C.double(srcBounds.Xmin),
+		C.double(srcBounds.Ymin),
+		C.double(srcBounds.Xmax),
+		C.double(srcBounds.Ymax),
 		(*C.double)(unsafe.Pointer(&bounds.Xmin)),
 		(*C.double)(unsafe.Pointer(&bounds.Ymin)),
 		(*C.double)(unsafe.Pointer(&bounds.Xmax)),
 		(*C.double)(unsafe.Pointer(&bounds.Ymax)),
 		21,
 	) == 0 {
-		return bounds, fmt.Errorf("error transforming bounds to WGS84 coordinates")
+		return bounds, fmt.Errorf("error transforming bounds to %v coordinates", crs)
 	}
 
 	return bounds, nil
@@ -234,10 +304,38 @@ func (d *Dataset) CRS() string {
 	return d.crs
 }
 
+// Return true if d and other have the same CRS, or neither has a CRS
+func (d *Dataset) SameCRS(other *Dataset) bool {
+	d.mustBeOpen()
+	other.mustBeOpen()
+
+	srs := C.GDALGetSpatialRef(d.ptr)
+	otherSRS := C.GDALGetSpatialRef(other.ptr)
+	if unsafe.Pointer(srs) == nil || unsafe.Pointer(otherSRS) == nil {
+		return unsafe.Pointer(srs) == unsafe.Pointer(otherSRS)
+	}
+	return C.OSRIsSame(srs, otherSRS) != 0
+}
+
+// Get the dtype of the first band, which is used when reading all bands
 func (d *Dataset) DType() string {
 	return d.dtype
 }
 
+// Get the dtype of band, numbered from 1
+func (d *Dataset) BandDType(band int) string {
+	d.mustBeOpen()
+
+	return d.dtypes[band-1]
+}
+
+// Get the number of bands
+func (d *Dataset) BandCount() int {
+	d.mustBeOpen()
+
+	return d.bandCount
+}
+
 func (d *Dataset) Window(bounds *affine.Bounds) *Window {
 	d.mustBeOpen()
 
@@ -256,10 +354,12 @@ func (d *Dataset) String() string {
 	}
 
 	geoBounds, _ := d.GeoBounds()
-	return fmt.Sprintf("%v (%v: %v, nodata: %v)\ndimensions: %v x %v pixels\ntransform:\n%v\nbounds: %v\ngeographic bounds: %v", d.path, d.driver, d.dtype, d.nodata, d.Width(), d.Height(), d.transform, d.bounds, geoBounds)
+	return fmt.Sprintf("%v (%v: %v, bands: %v, nodata: %v)\ndimensions: %v x %v pixels\ntransform:\n%v\nbounds: %v\ngeographic bounds: %v", d.path, d.driver, d.dtype, d.bandCount, d.nodata, d.Width(), d.Height(), d.transform, d.bounds, geoBounds)
 }
 
-func (d *Dataset) GetWarpedVRT(crs string) (*Dataset, error) {
+// Create a warped VRT of the dataset in crs, using resampling.  Cutline is
+// optional; if provided, pixels outside the cutline are masked.
+func (d *Dataset) GetWarpedVRT(crs string, resampling Resampling, cutline *Cutline) (*Dataset, error) {
 	d.mustBeOpen()
 
 	targetSRSName := C.CString(crs)
@@ -269,6 +369,13 @@ func (d *Dataset) GetWarpedVRT(crs string) (*Dataset, error) {
 	options := []string{
 		"SKIP_NOSOURCE=YES", "UNIFIED_SRC_NODATA=YES", "NUM_THREADS=1",
 	}
+	if cutline != nil {
+		wkt, err := cutline.pixelWKT(d)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, "CUTLINE="+wkt)
+	}
 	optsLength := len(options)
 	gdalOpts := make([]*C.char, optsLength+1)
 	for i := 0; i < len(options); i++ {
@@ -283,11 +390,20 @@ func (d *Dataset) GetWarpedVRT(crs string) (*Dataset, error) {
 	// use 1024 MB memory for warping (doesn't seem to help)
 	// warpOpts.dfWarpMemoryLimit = (C.double)(1024 * 1024 * 1024)
 
+	// if the dataset does not have nodata or alpha, an alpha band is added
+	// after the data bands and used as the mask of the VRT.  This masks
+	// pixels outside an internal mask, outside the cutline, and outside the
+	// dataset where it is rotated or reprojected, which would otherwise be
+	// read as valid zeros.
+	if d.maskFlags&(C.GMF_ALPHA|C.GMF_NODATA) == 0 {
+		warpOpts.nDstAlphaBand = C.int(d.bandCount + 1)
+	}
+
 	ptr := C.GDALAutoCreateWarpedVRT(
 		d.ptr,
 		C.GDALGetProjectionRef(d.ptr),
 		targetSRSName,
-		C.GDALResampleAlg(RESAMPLING_NEAREST),
+		C.GDALResampleAlg(resampling),
 		0,
 		warpOpts,
 	)
@@ -296,9 +412,137 @@ func (d *Dataset) GetWarpedVRT(crs string) (*Dataset, error) {
 		return nil, fmt.Errorf("could not create WarpedVRT")
 	}
 
-	return newDataset(fmt.Sprintf("WarpedVRT (src: %v)", d.path), ptr)
+	vrt, err := newDataset(fmt.Sprintf("WarpedVRT (src: %v)", d.path), ptr)
+	if err != nil {
+		C.GDALClose(ptr)
+		return nil, err
+	}
+
+	// the VRT does not retain PIXELTYPE=SIGNEDBYTE, so int8 data would
+	// otherwise be read as uint8, and an alpha band added for the mask is not
+	// a data band
+	vrt.dtype = d.dtype
+	vrt.dtypes = d.dtypes
+	vrt.bandCount = d.bandCount
+	vrt.nodata = d.nodata
+
+	return vrt, nil
+}
+
+// Get the minimum and maximum values of the first band from statistics
+// stored in the dataset, computing them if necessary.  If approx is true,
+// statistics may be computed from overviews or a subset of pixels.
+func (d *Dataset) Statistics(approx bool) (min float64, max float64, err error) {
+	d.mustBeOpen()
+
+	approxOK := 0
+	if approx {
+		approxOK = 1
+	}
+
+	var mean, stdDev C.double
+	var cMin, cMax C.double
+	if C.GDALGetRasterStatistics(
+		C.GDALGetRasterBand(d.ptr, 1),
+		C.int(approxOK),
+		1, // force computation if not already stored
+		&cMin,
+		&cMax,
+		&mean,
+		&stdDev,
+	) != C.CE_None {
+		return 0, 0, fmt.Errorf("could not get statistics")
+	}
+
+	return float64(cMin), float64(cMax), nil
+}
+
+// Get the colors of the color table of the first band, indexed by value,
+// and the category name of each value if available.  Returns nil colors if
+// the band does not have a color table.
+func (d *Dataset) ColorTable() (colors []color.NRGBA, labels []string, err error) {
+	d.mustBeOpen()
+
+	band := C.GDALGetRasterBand(d.ptr, 1)
+	colorTable := C.GDALGetRasterColorTable(band)
+	if unsafe.Pointer(colorTable) == nil {
+		return nil, nil, nil
+	}
+
+	interpretation := C.GDALGetPaletteInterpretation(colorTable)
+	if interpretation != C.GPI_RGB && interpretation != C.GPI_Gray {
+		return nil, nil, fmt.Errorf("color table interpretation not supported: %v", interpretation)
+	}
+
+	count := int(C.GDALGetColorEntryCount(colorTable))
+	colors = make([]color.NRGBA, count)
+	for i := 0; i < count; i++ {
+		entry := C.GDALGetColorEntry(colorTable, C.int(i))
+		if interpretation == C.GPI_Gray {
+			colors[i] = color.NRGBA{uint8(entry.c1), uint8(entry.c1), uint8(entry.c1), 255}
+		} else {
+			colors[i] = color.NRGBA{uint8(entry.c1), uint8(entry.c2), uint8(entry.c3), uint8(entry.c4)}
+		}
+	}
+
+	names := C.GDALGetRasterCategoryNames(band)
+	if names != nil {
+		namesSlice := (*[1 << 28]*C.char)(unsafe.Pointer(names))
+		for i := 0; namesSlice[i] != nil; i++ {
+			labels = append(labels, C.GoString(namesSlice[i]))
+		}
+	}
+
+	return colors, labels, nil
+}
+
+// Get the number of pixels of each value of the first band, indexed by
+// value; only valid for uint8 and uint16 data.  Nodata pixels are not counted.
+// If approx is true, counts may be computed from overviews or a subset of
+// pixels.
+func (d *Dataset) ValueCounts(approx bool) ([]uint64, error) {
+	d.mustBeOpen()
+
+	var size int
+	switch d.dtype {
+	case "uint8":
+		size = math.MaxUint8 + 1
+	case "uint16":
+		size = math.MaxUint16 + 1
+	default:
+		return nil, fmt.Errorf("value counts not supported for dtype: %v", d.dtype)
+	}
+
+	approxOK := 0
+	if approx {
+		approxOK = 1
+	}
+
+	histogram := make([]C.GUIntBig, size)
+	if C.GDALGetRasterHistogramEx(
+		C.GDALGetRasterBand(d.ptr, 1),
+		-0.5,
+		C.double(size)-0.5,
+		C.int(size),
+		&histogram[0],
+		0, // exclude values out of range
+		C.int(approxOK),
+		nil,
+		nil,
+	) != C.CE_None {
+		return nil, fmt.Errorf("could not get value counts")
+	}
+
+	counts := make([]uint64, size)
+	for i, count := range histogram {
+		counts[i] = uint64(count)
+	}
+	return counts, nil
 }
 
+// Read all bands into buffer, which must be of size bufferWidth *
+// bufferHeight * number of bands.  Bands are stored sequentially in buffer,
+// one after another, and are converted to the dtype of the first band.
 func (d *Dataset) Read(buffer interface{}, offsetX int, offsetY int, width int, height int, bufferWidth int, bufferHeight int) error {
 	d.mustBeOpen()
 
@@ -306,12 +550,23 @@ func (d *Dataset) Read(buffer interface{}, offsetX int, offsetY int, width int,
 
 	var bufferPtr unsafe.Pointer
 	switch typedBuffer := buffer.(type) {
+	case []int8:
+		// signed bytes are read as bytes and reinterpreted
+		bufferPtr = unsafe.Pointer(&typedBuffer[0])
 	case []uint8:
 		bufferPtr = unsafe.Pointer(&typedBuffer[0])
+	case []int16:
+		bufferPtr = unsafe.Pointer(&typedBuffer[0])
 	case []uint16:
 		bufferPtr = unsafe.Pointer(&typedBuffer[0])
+	case []int32:
+		bufferPtr = unsafe.Pointer(&typedBuffer[0])
 	case []uint32:
 		bufferPtr = unsafe.Pointer(&typedBuffer[0])
+	case []float32:
+		bufferPtr = unsafe.Pointer(&typedBuffer[0])
+	case []float64:
+		bufferPtr = unsafe.Pointer(&typedBuffer[0])
 	default:
 		panic("Other dtypes not yet supported for Read()")
 	}
@@ -327,11 +582,11 @@ func (d *Dataset) Read(buffer interface{}, offsetX int, offsetY int, width int,
 		C.int(bufferWidth),
 		C.int(bufferHeight),
 		gdalDataType,
-		C.int(1), // number of bands being written
-		nil,      // default to selecting first band for writing
-		0,        // pixel spacing (same as underlying data type)
-		0,        // line spacing (default)
-		0,        // band spacing (default)
+		C.int(d.bandCount), // number of bands being read
+		nil,                // default to selecting first bandCount bands for reading
+		0,                  // pixel spacing (same as underlying data type)
+		0,                  // line spacing (default)
+		0,                  // band spacing (default: bands are stored sequentially)
 	) != C.CE_None {
 		return fmt.Errorf("could not read data")
 	}
@@ -339,13 +594,131 @@ func (d *Dataset) Read(buffer interface{}, offsetX int, offsetY int, width int,
 	return nil
 }
 
-// Read a tile of data from a Mercator-projection VRT or dataset
-func (d *Dataset) ReadTile(buffer interface{}, tileTransform *affine.Affine, tileID *tiles.TileID, tileSize int) (hasData bool, err error) {
+// Read the mask of the dataset into mask, which must be of size
+// bufferWidth * bufferHeight.  Mask values are 0 where pixels are not valid,
+// e.g., nodata or outside an internal mask, 255 where pixels are valid, and
+// may be in between for alpha bands.  The mask of a dataset with nodata is
+// derived from nodata; pixels of multi-band data are only masked where all
+// bands equal nodata.
+func (d *Dataset) ReadMask(mask []uint8, offsetX int, offsetY int, width int, height int, bufferWidth int, bufferHeight int) error {
+	d.mustBeOpen()
+
+	if d.maskFlags&C.GMF_ALL_VALID != 0 {
+		array.Fill(mask, uint8(255))
+		return nil
+	}
+
+	if err := d.readBandMask(1, mask, offsetX, offsetY, width, height, bufferWidth, bufferHeight); err != nil {
+		return err
+	}
+
+	// the nodata mask of each band only covers that band, so a pixel is
+	// valid if any band is valid
+	if d.maskFlags&C.GMF_NODATA != 0 && d.bandCount > 1 {
+		bandMask := make([]uint8, bufferWidth*bufferHeight)
+		for i := 2; i <= d.bandCount; i++ {
+			if err := d.readBandMask(i, bandMask, offsetX, offsetY, width, height, bufferWidth, bufferHeight); err != nil {
+				return err
+			}
+			for j, value := range bandMask {
+				if value > mask[j] {
+					mask[j] = value
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Read the mask of band (1-based) into mask; see ReadMask()
+func (d *Dataset) readBandMask(band int, mask []uint8, offsetX int, offsetY int, width int, height int, bufferWidth int, bufferHeight int) error {
+	if C.GDALRasterIO(
+		C.GDALGetMaskBand(C.GDALGetRasterBand(d.ptr, C.int(band))),
+		C.GF_Read,
+		C.int(offsetX),
+		C.int(offsetY),
+		C.int(width),
+		C.int(height),
+		unsafe.Pointer(&mask[0]),
+		C.int(bufferWidth),
+		C.int(bufferHeight),
+		C.GDT_Byte,
+		0, // pixel spacing
+		0, // line spacing
+	) != C.CE_None {
+		return fmt.Errorf("could not read mask")
+	}
+
+	return nil
+}
+
+// Create an in-memory VRT of the dataset with nodata set to nodata for all
+// bands, or removed if nodata is nil.  The VRT reads from the dataset, which
+// is closed when the VRT is closed.
+func (d *Dataset) WithNodata(nodata interface{}) (*Dataset, error) {
+	d.mustBeOpen()
+
+	driverName := C.CString("VRT")
+	defer C.free(unsafe.Pointer(driverName))
+	emptyName := C.CString("")
+	defer C.free(unsafe.Pointer(emptyName))
+
+	ptr := C.GDALCreateCopy(C.GDALGetDriverByName(driverName), emptyName, d.ptr, 0, nil, nil, nil)
+	if unsafe.Pointer(ptr) == nil {
+		return nil, fmt.Errorf("could not create VRT")
+	}
+
+	for i := 0; i < d.bandCount; i++ {
+		band := C.GDALGetRasterBand(ptr, C.int(i+1))
+		var err C.CPLErr
+		if nodata == nil {
+			err = C.GDALDeleteRasterNoDataValue(band)
+		} else {
+			err = C.GDALSetRasterNoDataValue(band, C.double(array.ToFloat(nodata)))
+		}
+		if err != C.CE_None {
+			C.GDALClose(ptr)
+			return nil, fmt.Errorf("could not set nodata")
+		}
+	}
+
+	vrt, err := newDataset(d.path, ptr)
+	if err != nil {
+		C.GDALClose(ptr)
+		return nil, err
+	}
+	vrt.dtype = d.dtype
+	vrt.dtypes = d.dtypes
+	vrt.nodata = nodata
+	vrt.source = d
+
+	return vrt, nil
+}
+
+// Coverage of a tile by the data of a dataset
+type Coverage int
+
+const (
+	// tile does not have data
+	NotCovered Coverage = iota
+	// tile extends beyond the dataset or includes pixels that are not valid
+	// according to the mask; areas outside are filled with nodata
+	PartiallyCovered
+	// tile is entirely within the dataset
+	FullyCovered
+)
+
+// Read a tile of tile matrix set tms from a VRT or dataset in the CRS of tms.
+// Buffer must be of size tileSize * tileSize * number of bands; see Read().
+// Mask must be of size tileSize * tileSize, and is set to 0 outside the
+// dataset; see ReadMask().
+func (d *Dataset) ReadTile(buffer interface{}, mask []uint8, tileTransform *affine.Affine, tms *tiles.TileMatrixSet, tileID *tiles.TileID, tileSize int) (coverage Coverage, err error) {
 	size := float64(tileSize)
 	vrtWidth := float64(d.width)
 	vrtHeight := float64(d.height)
 
-	tileBounds := tileID.MercatorBounds()
+	tileBounds := tms.Bounds(tileID)
 	window := d.Window(tileBounds)
 	tileTransform = d.WindowTransform(window)
 
@@ -368,11 +741,18 @@ func (d *Dataset) ReadTile(buffer interface{}, tileTransform *affine.Affine, til
 	readWidth := int(math.Floor((xStop - xStart) + 0.5))
 	readHeight := int(math.Floor((yStop - yStart) + 0.5))
 
-	array.Fill(buffer, d.nodata)
+	// areas outside the dataset are filled with nodata, or 0 if not set
+	// (fully transparent for RGBA data)
+	fillValue := d.nodata
+	if fillValue == nil {
+		fillValue = array.ZeroValue(d.dtype)
+	}
+	array.Fill(buffer, fillValue)
+	array.Fill(mask, uint8(0))
 
 	if readWidth <= 0 || readHeight <= 0 {
 		// no tile available
-		hasData = false
+		coverage = NotCovered
 		return
 	}
 
@@ -381,40 +761,57 @@ func (d *Dataset) ReadTile(buffer interface{}, tileTransform *affine.Affine, til
 		if err != nil {
 			return
 		}
-
-		if array.AllEquals(buffer, d.nodata) {
-			// tile is empty
-			hasData = false
+		err = d.ReadMask(mask, int(xStart), int(yStart), readWidth, readHeight, width, height)
+		if err != nil {
 			return
 		}
-		return true, nil
+
+		return maskCoverage(mask, FullyCovered), nil
 	}
 
 	// TODO: figure out how to use buffer for reading via GDAL without data
 	// getting striped, then get a buffer to paste into from sync.Pool
 
 	// only part of tile could be read, need to allocate a new buffer to receive data
-	var readBuffer interface{}
-	switch buffer.(type) {
-	case []uint8:
-		readBuffer = make([]uint8, width*height)
-	case []uint16:
-		readBuffer = make([]uint16, width*height)
-	case []uint32:
-		readBuffer = make([]uint32, width*height)
-
-	default:
-		panic("Other dtypes not yet supported for ReadTile()")
-	}
+	readBuffer := array.NewBuffer(d.dtype, width*height*d.bandCount)
 
 	err = d.Read(readBuffer, int(xStart), int(yStart), readWidth, readHeight, width, height)
 	if err != nil {
 		return
 	}
+	readMask := make([]uint8, width*height)
+	err = d.ReadMask(readMask, int(xStart), int(yStart), readWidth, readHeight, width, height)
+	if err != nil {
+		return
+	}
+	array.Paste(mask, tileSize, tileSize, readMask, height, width, int(topOffset), int(leftOffset))
+
+	// paste each band separately
+	tileBandSize := tileSize * tileSize
+	readBandSize := width * height
+	for band := 0; band < d.bandCount; band++ {
+		array.Paste(
+			array.Slice(buffer, band*tileBandSize, (band+1)*tileBandSize), tileSize, tileSize,
+			array.Slice(readBuffer, band*readBandSize, (band+1)*readBandSize), height, width,
+			int(topOffset), int(leftOffset),
+		)
+	}
 
-	array.Paste(buffer, tileSize, tileSize, readBuffer, height, width, int(topOffset), int(leftOffset))
+	return maskCoverage(mask, PartiallyCovered), nil
+}
 
-	return true, nil
+// Get coverage of a tile from its mask: NotCovered if no pixels are valid,
+// otherwise PartiallyCovered if any pixels are not fully valid
+func maskCoverage(mask []uint8, coverage Coverage) Coverage {
+	if array.AllEquals(mask, uint8(0)) {
+		return NotCovered
+	}
+	for _, value := range mask {
+		if value != 255 {
+			return PartiallyCovered
+		}
+	}
+	return coverage
 }
 
 func WriteGeoTIFF(filename string, data *Array, transform *affine.Affine, crs string, nodata interface{}) error {
@@ -436,6 +833,10 @@ func WriteGeoTIFF(filename string, data *Array, transform *affine.Affine, crs st
 		bufferPtr = unsafe.Pointer(&bufferType[0])
 	case []uint32:
 		bufferPtr = unsafe.Pointer(&bufferType[0])
+	case []float32:
+		bufferPtr = unsafe.Pointer(&bufferType[0])
+	case []float64:
+		bufferPtr = unsafe.Pointer(&bufferType[0])
 	}
 
 	driverName := C.CString("GTiff")
@@ -512,6 +913,10 @@ func WriteGeoTIFF(filename string, data *Array, transform *affine.Affine, crs st
 		cNodata = C.double(typedNodata)
 	case uint32:
 		cNodata = C.double(typedNodata)
+	case float32:
+		cNodata = C.double(typedNodata)
+	case float64:
+		cNodata = C.double(typedNodata)
 	}
 
 	if C.GDALSetRasterNoDataValue(band, cNodata) != C.CE_None {
@@ -0,0 +1,93 @@
+package gdal
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/brendan-ward/rastertiler/affine"
+)
+
+func TestWarpedVRTRotatedMask(t *testing.T) {
+	// 10x10 pixels of 10m rotated 45 degrees, without nodata or alpha
+	s := 10 * math.Sqrt2 / 2
+	transform := &affine.Affine{A: s, B: s, C: 0, D: s, E: -s, F: 0}
+	filename := filepath.Join(t.TempDir(), "rotated.tif")
+	if err := WriteGeoTIFF(filename, NewArray(10, 10, "uint8", uint8(1)), transform, "EPSG:3857", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	vrt, err := d.GetWarpedVRT("EPSG:3857", Nearest, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer vrt.Close()
+
+	width := vrt.Width()
+	height := vrt.Height()
+	mask := make([]uint8, width*height)
+	if err = vrt.ReadMask(mask, 0, 0, width, height, width, height); err != nil {
+		t.Fatal(err)
+	}
+
+	// corners of the north-up VRT are outside the rotated dataset
+	for _, i := range []int{0, width - 1, (height - 1) * width, height*width - 1} {
+		if mask[i] != 0 {
+			t.Errorf("pixel %v outside rotated dataset is not masked: %v", i, mask[i])
+		}
+	}
+	if center := mask[(height/2)*width+width/2]; center != 255 {
+		t.Errorf("pixel at center of rotated dataset is masked: %v", center)
+	}
+}
+
+func TestReadMaskRGBNodata(t *testing.T) {
+	// 3x1 pixels of 3 bands with nodata 0: (0, 255, 0), (0, 0, 0), (10, 20, 30)
+	dir := t.TempDir()
+	transform := &affine.Affine{A: 10, E: -10, F: 10}
+	bands := [][]uint8{{0, 0, 10}, {255, 0, 20}, {0, 0, 30}}
+	var vrtBands strings.Builder
+	for i, values := range bands {
+		data := NewArray(3, 1, "uint8", uint8(0))
+		for col, value := range values {
+			data.Set(0, col, value)
+		}
+		filename := filepath.Join(dir, fmt.Sprintf("band%v.tif", i+1))
+		if err := WriteGeoTIFF(filename, data, transform, "EPSG:3857", nil); err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(&vrtBands, `<VRTRasterBand dataType="Byte" band="%v"><NoDataValue>0</NoDataValue><SimpleSource><SourceFilename>%v</SourceFilename><SourceBand>1</SourceBand></SimpleSource></VRTRasterBand>`, i+1, filename)
+	}
+	filename := filepath.Join(dir, "rgb.vrt")
+	vrtXML := `<VRTDataset rasterXSize="3" rasterYSize="1"><SRS>EPSG:3857</SRS><GeoTransform>0, 10, 0, 10, 0, -10</GeoTransform>` + vrtBands.String() + `</VRTDataset>`
+	if err := os.WriteFile(filename, []byte(vrtXML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	// pixels are only masked where all bands equal nodata
+	mask := make([]uint8, 3)
+	if err = d.ReadMask(mask, 0, 0, 3, 1, 3, 1); err != nil {
+		t.Fatal(err)
+	}
+	expected := []uint8{255, 0, 255}
+	for i, value := range expected {
+		if mask[i] != value {
+			t.Errorf("pixel %v: mask %v not expected value: %v", i, mask[i], value)
+		}
+	}
+}
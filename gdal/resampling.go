@@ -0,0 +1,55 @@
+package gdal
+
+// #include "gdalwarper.h"
+import "C"
+import (
+	"fmt"
+	"strings"
+)
+
+// Resampling algorithm used when warping
+type Resampling int
+
+const (
+	Nearest     Resampling = C.GRA_NearestNeighbour
+	Bilinear    Resampling = C.GRA_Bilinear
+	Cubic       Resampling = C.GRA_Cubic
+	CubicSpline Resampling = C.GRA_CubicSpline
+	Lanczos     Resampling = C.GRA_Lanczos
+	Average     Resampling = C.GRA_Average
+	Mode        Resampling = C.GRA_Mode
+	Max         Resampling = C.GRA_Max
+	Min         Resampling = C.GRA_Min
+	Median      Resampling = C.GRA_Med
+	Q1          Resampling = C.GRA_Q1
+	Q3          Resampling = C.GRA_Q3
+)
+
+var resamplingNames = map[Resampling]string{
+	Nearest:     "nearest",
+	Bilinear:    "bilinear",
+	Cubic:       "cubic",
+	CubicSpline: "cubicspline",
+	Lanczos:     "lanczos",
+	Average:     "average",
+	Mode:        "mode",
+	Max:         "max",
+	Min:         "min",
+	Median:      "med",
+	Q1:          "q1",
+	Q3:          "q3",
+}
+
+// Parse resampling name, using the same names as gdalwarp
+func ParseResampling(name string) (Resampling, error) {
+	for resampling, resamplingName := range resamplingNames {
+		if resamplingName == strings.ToLower(name) {
+			return resampling, nil
+		}
+	}
+	return Nearest, fmt.Errorf("resampling must be one of nearest, bilinear, cubic, cubicspline, lanczos, average, mode, min, max, med, q1, q3: %v", name)
+}
+
+func (r Resampling) String() string {
+	return resamplingNames[r]
+}
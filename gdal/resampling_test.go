@@ -0,0 +1,23 @@
+package gdal
+
+import "testing"
+
+func TestParseResampling(t *testing.T) {
+	for resampling, name := range resamplingNames {
+		value, err := ParseResampling(name)
+		if err != nil {
+			t.Error(err)
+		}
+		if value != resampling {
+			t.Errorf("%v: %v does not match expected resampling %v", name, value, resampling)
+		}
+	}
+
+	if value, _ := ParseResampling("Bilinear"); value != Bilinear {
+		t.Errorf("ParseResampling() is not case insensitive")
+	}
+
+	if _, err := ParseResampling("foo"); err == nil {
+		t.Errorf("ParseResampling() did not return error for invalid resampling")
+	}
+}
@@ -0,0 +1,64 @@
+package gdal
+
+// #include "gdal_utils.h"
+// #include "cpl_vsi.h"
+import "C"
+import (
+	"fmt"
+	"sync/atomic"
+	"unsafe"
+)
+
+// Build a mosaic VRT of source filenames in an in-memory file, using
+// gdalbuildvrt options, e.g., []string{"-resolution", "highest"}.  Where
+// sources overlap, values are taken from the last source.  Returns the
+// filename of the VRT, which may be opened using Open() any number of times;
+// remove it using RemoveFile() when no longer needed.
+func BuildVRT(sources []string, options []string) (string, error) {
+	if len(sources) == 0 {
+		return "", fmt.Errorf("at least one source is required to build a VRT")
+	}
+
+	// create null-terminated C string arrays
+	cSources := make([]*C.char, len(sources)+1)
+	for i, source := range sources {
+		cSources[i] = C.CString(source)
+		defer C.free(unsafe.Pointer(cSources[i]))
+	}
+	cSources[len(sources)] = (*C.char)(unsafe.Pointer(nil))
+
+	cOptions := make([]*C.char, len(options)+1)
+	for i, option := range options {
+		cOptions[i] = C.CString(option)
+		defer C.free(unsafe.Pointer(cOptions[i]))
+	}
+	cOptions[len(options)] = (*C.char)(unsafe.Pointer(nil))
+
+	vrtOptions := C.GDALBuildVRTOptionsNew((**C.char)(unsafe.Pointer(&cOptions[0])), nil)
+	if vrtOptions == nil {
+		return "", fmt.Errorf("invalid VRT options: %v", options)
+	}
+	defer C.GDALBuildVRTOptionsFree(vrtOptions)
+
+	filename := fmt.Sprintf("/vsimem/rastertiler_%v.vrt", atomic.AddUint64(&vsimemCounter, 1))
+	cFilename := C.CString(filename)
+	defer C.free(unsafe.Pointer(cFilename))
+
+	var usageError C.int
+	ptr := C.GDALBuildVRT(cFilename, C.int(len(sources)), nil, (**C.char)(unsafe.Pointer(&cSources[0])), vrtOptions, &usageError)
+	if unsafe.Pointer(ptr) == nil {
+		return "", fmt.Errorf("could not build VRT: %v", C.GoString(C.CPLGetLastErrorMsg()))
+	}
+	// closing the VRT writes it to the in-memory file
+	C.GDALClose(ptr)
+
+	return filename, nil
+}
+
+// Remove a file, such as an in-memory file created by BuildVRT()
+func RemoveFile(filename string) {
+	cFilename := C.CString(filename)
+	defer C.free(unsafe.Pointer(cFilename))
+
+	C.VSIUnlink(cFilename)
+}
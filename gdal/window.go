@@ -2,7 +2,6 @@ package gdal
 
 import (
 	"fmt"
-	"math"
 
 	"github.com/brendan-ward/rastertiler/affine"
 )
@@ -18,56 +17,17 @@ func (w *Window) String() string {
 	return fmt.Sprintf("Window(xoff: %v, yoff: %v, width: %v, height: %v)", w.XOffset, w.YOffset, w.Width, w.Height)
 }
 
-// Calculate Window based on Affine transform and bounds
+// Calculate Window based on Affine transform and bounds.  The window
+// contains all four corners of bounds, so it is larger than bounds if the
+// transform includes rotation.
 func WindowFromBounds(transform *affine.Affine, bounds *affine.Bounds) *Window {
-	invTransform := transform.Invert()
-
-	// calculate outer bounds
-	xmin := math.Inf(1)
-	ymin := math.Inf(1)
-	xmax := math.Inf(-1)
-	ymax := math.Inf(-1)
-
-	xs := [4]float64{}
-	ys := [4]float64{}
-	var x float64
-	var y float64
-	x, y = invTransform.Multiply(bounds.Xmin, bounds.Ymin)
-	xs[0] = x
-	ys[0] = y
-
-	x, y = invTransform.Multiply(bounds.Xmin, bounds.Ymax)
-	xs[1] = x
-	ys[1] = y
-
-	x, y = invTransform.Multiply(bounds.Xmax, bounds.Ymin)
-	xs[2] = x
-	ys[2] = y
-
-	x, y = invTransform.Multiply(bounds.Xmax, bounds.Ymax)
-	xs[3] = x
-	ys[3] = y
-
-	for i := 0; i < 4; i++ {
-		if xs[i] < xmin {
-			xmin = xs[i]
-		}
-		if ys[i] < ymin {
-			ymin = ys[i]
-		}
-		if xs[i] > xmax {
-			xmax = xs[i]
-		}
-		if ys[i] > ymax {
-			ymax = ys[i]
-		}
-	}
+	pixelBounds := transform.Invert().TransformBounds(bounds)
 
 	return &Window{
-		XOffset: xmin,
-		YOffset: ymin,
-		Width:   xmax - xmin,
-		Height:  ymax - ymin,
+		XOffset: pixelBounds.Xmin,
+		YOffset: pixelBounds.Ymin,
+		Width:   pixelBounds.Xmax - pixelBounds.Xmin,
+		Height:  pixelBounds.Ymax - pixelBounds.Ymin,
 	}
 }
 
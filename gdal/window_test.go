@@ -68,3 +68,31 @@ func TestTransform(t *testing.T) {
 	}
 
 }
+
+func TestWindowFromBoundsRotated(t *testing.T) {
+	tests := []struct {
+		transform *affine.Affine
+		bounds    *affine.Bounds
+		expected  Window
+	}{
+		// south-up, anchored from bottom left
+		{
+			transform: &affine.Affine{A: 30, B: 0, C: 1000, D: 0, E: 30, F: 2000},
+			bounds:    &affine.Bounds{Xmin: 1000, Ymin: 2000, Xmax: 1300, Ymax: 2600},
+			expected:  Window{XOffset: 0, YOffset: 0, Width: 10, Height: 20},
+		},
+		// rotated 90 degrees
+		{
+			transform: &affine.Affine{A: 0, B: 10, C: 0, D: 10, E: 0, F: 0},
+			bounds:    &affine.Bounds{Xmin: 0, Ymin: 0, Xmax: 200, Ymax: 100},
+			expected:  Window{XOffset: 0, YOffset: 0, Width: 10, Height: 20},
+		},
+	}
+
+	for _, tc := range tests {
+		window := WindowFromBounds(tc.transform, tc.bounds)
+		if *window != tc.expected {
+			t.Errorf("%v not expected value: %v", window, tc.expected)
+		}
+	}
+}
@@ -11,13 +11,15 @@ import (
 	"crawshaw.io/sqlite"
 	"crawshaw.io/sqlite/sqlitex"
 	"github.com/brendan-ward/rastertiler/affine"
+	"github.com/brendan-ward/rastertiler/tilejson"
 	"github.com/brendan-ward/rastertiler/tiles"
 )
 
 var emptyContext context.Context
 
 type MBtilesWriter struct {
-	pool *sqlitex.Pool
+	pool     *sqlitex.Pool
+	existing bool // true if opened from an existing file
 }
 
 // Create a tiles table structure that allows us to de-duplicate tile images
@@ -43,6 +45,7 @@ const index_sql = `
 CREATE UNIQUE INDEX IF NOT EXISTS map_index ON map (zoom_level, tile_column, tile_row);
 `
 
+// Create a new MBtilesWriter, overwriting any existing file at path
 func NewMBtilesWriter(path string, poolsize int) (*MBtilesWriter, error) {
 	ext := filepath.Ext(path)
 	if ext != ".mbtiles" {
@@ -54,6 +57,38 @@ func NewMBtilesWriter(path string, poolsize int) (*MBtilesWriter, error) {
 		os.Remove(path)
 	}
 
+	return openMBtilesWriter(path, poolsize)
+}
+
+// Open an existing MBTiles file for writing, keeping all tiles and metadata
+// that were previously written to it
+func OpenMBtilesWriter(path string, poolsize int) (*MBtilesWriter, error) {
+	ext := filepath.Ext(path)
+	if ext != ".mbtiles" {
+		return nil, fmt.Errorf("path must end in .mbtiles")
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, fmt.Errorf("mbtiles file '%s' does not exist", path)
+	}
+
+	db, err := openMBtilesWriter(path, poolsize)
+	if err != nil {
+		return nil, err
+	}
+
+	db.existing = true
+
+	// unique index is required so that existing tiles can be replaced
+	if err = db.CreateIndexes(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+func openMBtilesWriter(path string, poolsize int) (*MBtilesWriter, error) {
 	// check flags: this may not be safe for multiple goroutines (only one write  per connection though)
 	pool, err := sqlitex.Open(path, sqlite.SQLITE_OPEN_CREATE|sqlite.SQLITE_OPEN_READWRITE|sqlite.SQLITE_OPEN_NOMUTEX|sqlite.SQLITE_OPEN_WAL, poolsize)
 	if err != nil {
@@ -117,10 +152,24 @@ func (db *MBtilesWriter) CloseConnection(con *sqlite.Conn) {
 }
 
 func writeMetadataItem(con *sqlite.Conn, key string, value interface{}) error {
-	return sqlitex.Exec(con, "INSERT INTO metadata (name,value) VALUES (?, ?)", nil, key, value)
+	return sqlitex.Exec(con, "INSERT OR REPLACE INTO metadata (name,value) VALUES (?, ?)", nil, key, value)
+}
+
+func readMetadata(con *sqlite.Conn) (map[string]string, error) {
+	metadata := make(map[string]string)
+	err := sqlitex.Exec(con, "SELECT name, value FROM metadata", func(stmt *sqlite.Stmt) error {
+		metadata[stmt.ColumnText(0)] = stmt.ColumnText(1)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not read metadata: %q", err)
+	}
+	return metadata, nil
 }
 
-func (db *MBtilesWriter) WriteMetadata(name string, description string, attribution string, minZoom uint8, maxZoom uint8, bounds *affine.Bounds) (err error) {
+// Write a single additional metadata item, such as a value used to identify
+// how the tileset was created
+func (db *MBtilesWriter) WriteMetadataItem(key string, value interface{}) error {
 	if db == nil || db.pool == nil {
 		return fmt.Errorf("cannot write to closed mbtiles database")
 	}
@@ -131,42 +180,76 @@ func (db *MBtilesWriter) WriteMetadata(name string, description string, attribut
 	}
 	defer db.CloseConnection(con)
 
-	// create savepoint
-	defer sqlitex.Save(con)(&err)
+	return writeMetadataItem(con, key, value)
+}
 
-	if err = writeMetadataItem(con, "name", name); err != nil {
-		return err
-	}
-	if description != "" {
-		if err = writeMetadataItem(con, "description", description); err != nil {
-			return err
-		}
+// Read all metadata items as a map of name to value
+func (db *MBtilesWriter) ReadMetadata() (map[string]string, error) {
+	if db == nil || db.pool == nil {
+		return nil, fmt.Errorf("cannot read from closed mbtiles database")
 	}
-	if attribution != "" {
-		if err = writeMetadataItem(con, "attribution", attribution); err != nil {
-			return err
-		}
+
+	con, err := db.GetConnection()
+	if err != nil {
+		return nil, err
 	}
-	if err = writeMetadataItem(con, "minzoom", minZoom); err != nil {
-		return err
+	defer db.CloseConnection(con)
+
+	return readMetadata(con)
+}
+
+// Read the IDs of all tiles already written to the map table.  Tile Y is
+// flipped from the mbtiles spec back to tiles numbered from upper left.
+func (db *MBtilesWriter) ReadTileIDs() (map[tiles.TileID]bool, error) {
+	if db == nil || db.pool == nil {
+		return nil, fmt.Errorf("cannot read from closed mbtiles database")
 	}
-	if err = writeMetadataItem(con, "maxzoom", maxZoom); err != nil {
-		return err
+
+	con, err := db.GetConnection()
+	if err != nil {
+		return nil, err
 	}
-	if err = writeMetadataItem(con, "center", fmt.Sprintf("%.5f,%.5f,%v", (bounds.Xmax-bounds.Xmin)/2.0, (bounds.Ymax-bounds.Ymin)/2.0, minZoom)); err != nil {
-		return err
+	defer db.CloseConnection(con)
+
+	tileIDs := make(map[tiles.TileID]bool)
+	err = sqlitex.Exec(con, "SELECT zoom_level, tile_column, tile_row FROM map", func(stmt *sqlite.Stmt) error {
+		zoom := uint8(stmt.ColumnInt64(0))
+		y := uint32((int64(1) << zoom) - 1 - stmt.ColumnInt64(2))
+		tileIDs[tiles.TileID{Zoom: zoom, X: uint32(stmt.ColumnInt64(1)), Y: y}] = true
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not read existing tiles: %q", err)
 	}
-	if err = writeMetadataItem(con, "bounds", fmt.Sprintf("%.5f,%.5f,%.5f,%.5f", bounds.Xmin, bounds.Ymin, bounds.Xmax, bounds.Ymax)); err != nil {
-		return err
+
+	return tileIDs, nil
+}
+
+func (db *MBtilesWriter) WriteMetadata(name string, description string, attribution string, minZoom uint8, maxZoom uint8, bounds *affine.Bounds, format string) (err error) {
+	if db == nil || db.pool == nil {
+		return fmt.Errorf("cannot write to closed mbtiles database")
 	}
-	if err = writeMetadataItem(con, "type", "overlay"); err != nil {
+
+	con, err := db.GetConnection()
+	if err != nil {
 		return err
 	}
-	if err = writeMetadataItem(con, "format", "png"); err != nil {
+	defer db.CloseConnection(con)
+
+	// create savepoint
+	defer sqlitex.Save(con)(&err)
+
+	// merge with metadata of any tiles previously written to this tileset
+	existing, err := readMetadata(con)
+	if err != nil {
 		return err
 	}
-	if err = writeMetadataItem(con, "version", "1.0.0"); err != nil {
-		return err
+	minZoom, maxZoom, bounds = tilejson.MergeMetadata(existing, minZoom, maxZoom, bounds)
+
+	for key, value := range tilejson.NewMetadata(name, description, attribution, minZoom, maxZoom, bounds, format) {
+		if err = writeMetadataItem(con, key, value); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -200,8 +283,9 @@ func WriteTile(con *sqlite.Conn, tile *tiles.TileID, png []byte) (err error) {
 		return fmt.Errorf("could not write tile %v to mbtiles: %q", tile, err)
 	}
 
-	// tile zoom, x, y should always be unique
-	err = sqlitex.Exec(con, "INSERT INTO map (zoom_level, tile_column, tile_row, tile_id) values(?, ?, ?, ?)",
+	// tile zoom, x, y should always be unique; replace any existing tile
+	// if the unique index is present
+	err = sqlitex.Exec(con, "INSERT OR REPLACE INTO map (zoom_level, tile_column, tile_row, tile_id) values(?, ?, ?, ?)",
 		nil, tile.Zoom, tile.X, y, id)
 	if err != nil {
 		return fmt.Errorf("could not write tile %v to mbtiles: %q", tile, err)
@@ -210,6 +294,52 @@ func WriteTile(con *sqlite.Conn, tile *tiles.TileID, png []byte) (err error) {
 	return nil
 }
 
+func (db *MBtilesWriter) DeleteTile(tile *tiles.TileID) error {
+	con, err := db.GetConnection()
+	if err != nil {
+		return err
+	}
+	defer db.CloseConnection(con)
+
+	return DeleteTile(con, tile)
+}
+
+// Delete the tile from the open connection, if present.  The tile image is
+// retained until RemoveUnusedImages is called.
+func DeleteTile(con *sqlite.Conn, tile *tiles.TileID) error {
+	// flip tile Y to match mbtiles spec
+	y := (1 << tile.Zoom) - 1 - tile.Y
+
+	err := sqlitex.Exec(con, "DELETE FROM map WHERE zoom_level = ? AND tile_column = ? AND tile_row = ?",
+		nil, tile.Zoom, tile.X, y)
+	if err != nil {
+		return fmt.Errorf("could not delete tile %v from mbtiles: %q", tile, err)
+	}
+
+	return nil
+}
+
+// Remove tile images that are no longer referenced by any tile, such as
+// after tiles have been replaced or deleted
+func (db *MBtilesWriter) RemoveUnusedImages() error {
+	if db == nil || db.pool == nil {
+		return fmt.Errorf("cannot write to closed mbtiles database")
+	}
+
+	con, err := db.GetConnection()
+	if err != nil {
+		return err
+	}
+	defer db.CloseConnection(con)
+
+	err = sqlitex.Exec(con, "DELETE FROM images WHERE tile_id NOT IN (SELECT tile_id FROM map)", nil)
+	if err != nil {
+		return fmt.Errorf("could not remove unused images: %q", err)
+	}
+
+	return nil
+}
+
 func (db *MBtilesWriter) CreateIndexes() error {
 	if db == nil || db.pool == nil {
 		return fmt.Errorf("cannot write to closed mbtiles database")
@@ -228,3 +358,15 @@ func (db *MBtilesWriter) CreateIndexes() error {
 
 	return nil
 }
+
+// Create indexes and, if tiles may have been replaced in an existing file,
+// remove unused tile images
+func (db *MBtilesWriter) Finalize() error {
+	if err := db.CreateIndexes(); err != nil {
+		return err
+	}
+	if db.existing {
+		return db.RemoveUnusedImages()
+	}
+	return nil
+}
@@ -0,0 +1,253 @@
+package mbtiles
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"github.com/brendan-ward/rastertiler/affine"
+	"github.com/brendan-ward/rastertiler/tiles"
+)
+
+// Count the rows of table
+func countRows(t *testing.T, db *MBtilesWriter, table string) int {
+	con, err := db.GetConnection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.CloseConnection(con)
+
+	count := 0
+	err = sqlitex.Exec(con, "SELECT count(*) FROM "+table, func(stmt *sqlite.Stmt) error {
+		count = int(stmt.ColumnInt64(0))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+// Create an mbtiles file with tiles that all have the same data
+func createTileset(t *testing.T, filename string, tileIDs []*tiles.TileID, data []byte) {
+	db, err := NewMBtilesWriter(filename, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	bounds := &affine.Bounds{Xmin: -10, Ymin: -5, Xmax: 10, Ymax: 5}
+	if err = db.WriteMetadata("test", "", "", 1, 2, bounds, "png"); err != nil {
+		t.Fatal(err)
+	}
+	if err = db.WriteMetadataItem("source", "test.tif"); err != nil {
+		t.Fatal(err)
+	}
+	for _, tileID := range tileIDs {
+		if err = db.WriteTile(tileID, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = db.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Read the data of tile, or nil if it is not present
+func readTile(t *testing.T, db *MBtilesWriter, tile *tiles.TileID) []byte {
+	con, err := db.GetConnection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.CloseConnection(con)
+
+	// flip tile Y to match mbtiles spec
+	y := (1 << tile.Zoom) - 1 - tile.Y
+	var data []byte
+	err = sqlitex.Exec(con, "SELECT tile_data FROM tiles WHERE zoom_level = ? AND tile_column = ? AND tile_row = ?", func(stmt *sqlite.Stmt) error {
+		data = make([]byte, stmt.ColumnLen(0))
+		stmt.ColumnBytes(0, data)
+		return nil
+	}, tile.Zoom, tile.X, y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestMBtilesWriterRoundTrip(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.mbtiles")
+	tileIDs := []*tiles.TileID{tiles.NewTileID(1, 0, 0), tiles.NewTileID(2, 1, 0), tiles.NewTileID(2, 3, 2)}
+	createTileset(t, filename, tileIDs, []byte("tile"))
+
+	reader, err := NewMBtilesReader(filename, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	for _, tileID := range tileIDs {
+		data, _, err := reader.ReadTile(tileID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "tile" {
+			t.Errorf("%v: %q not expected value: tile", tileID, data)
+		}
+	}
+
+	// tile at flipped Y is not present
+	if data, _, _ := reader.ReadTile(tiles.NewTileID(2, 1, 3)); data != nil {
+		t.Errorf("tile with flipped Y should not be present")
+	}
+
+	metadata, err := reader.ReadMetadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata["minzoom"] != "1" || metadata["maxzoom"] != "2" || metadata["source"] != "test.tif" {
+		t.Errorf("metadata not expected values: %v", metadata)
+	}
+}
+
+func TestNewMBtilesWriterOverwrites(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.mbtiles")
+	createTileset(t, filename, []*tiles.TileID{tiles.NewTileID(1, 0, 0)}, []byte("tile"))
+
+	db, err := NewMBtilesWriter(filename, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if count := countRows(t, db, "map"); count != 0 {
+		t.Errorf("new tileset should not have any tiles, has %v", count)
+	}
+}
+
+func TestOpenMBtilesWriterErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := OpenMBtilesWriter(filepath.Join(dir, "missing.mbtiles"), 1); err == nil {
+		t.Errorf("OpenMBtilesWriter() did not return error for missing file")
+	}
+	if _, err := OpenMBtilesWriter(filepath.Join(dir, "test.db"), 1); err == nil {
+		t.Errorf("OpenMBtilesWriter() did not return error for wrong extension")
+	}
+}
+
+func TestMBtilesWriterResume(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.mbtiles")
+	createTileset(t, filename, []*tiles.TileID{tiles.NewTileID(1, 0, 0), tiles.NewTileID(2, 1, 0)}, []byte("tile"))
+
+	db, err := OpenMBtilesWriter(filename, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	metadata, err := db.ReadMetadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata["source"] != "test.tif" {
+		t.Errorf("metadata of existing tileset not retained: %v", metadata)
+	}
+
+	// Y is flipped back to tiles numbered from upper left
+	existing, err := db.ReadTileIDs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[tiles.TileID]bool{{Zoom: 1, X: 0, Y: 0}: true, {Zoom: 2, X: 1, Y: 0}: true}
+	if len(existing) != len(expected) {
+		t.Fatalf("existing tiles %v do not match expected: %v", existing, expected)
+	}
+	for tileID := range expected {
+		if !existing[tileID] {
+			t.Errorf("tile %v not in existing tiles: %v", tileID, existing)
+		}
+	}
+
+	if err = db.WriteTile(tiles.NewTileID(2, 3, 3), []byte("tile")); err != nil {
+		t.Fatal(err)
+	}
+	if err = db.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+
+	existing, err = db.ReadTileIDs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(existing) != 3 || !existing[tiles.TileID{Zoom: 2, X: 3, Y: 3}] {
+		t.Errorf("resumed tile not added to existing tiles: %v", existing)
+	}
+}
+
+func TestMBtilesWriterUpdate(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.mbtiles")
+	createTileset(t, filename, []*tiles.TileID{tiles.NewTileID(1, 0, 0), tiles.NewTileID(2, 1, 0), tiles.NewTileID(2, 2, 0)}, []byte("old"))
+
+	db, err := OpenMBtilesWriter(filename, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// zoom levels and bounds are merged with existing metadata
+	bounds := &affine.Bounds{Xmin: 0, Ymin: 0, Xmax: 20, Ymax: 10}
+	if err = db.WriteMetadata("test", "", "", 2, 3, bounds, "png"); err != nil {
+		t.Fatal(err)
+	}
+
+	// replace one tile, delete another, and add a new one
+	if err = db.WriteTile(tiles.NewTileID(2, 1, 0), []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	if err = db.DeleteTile(tiles.NewTileID(2, 2, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if err = db.WriteTile(tiles.NewTileID(3, 0, 0), []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	// deleting a tile that is not present is not an error
+	if err = db.DeleteTile(tiles.NewTileID(3, 7, 7)); err != nil {
+		t.Fatal(err)
+	}
+
+	if count := countRows(t, db, "map"); count != 3 {
+		t.Errorf("replaced tile should not be duplicated: %v tiles", count)
+	}
+
+	// only images of tiles that remain are kept
+	if err = db.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+	if count := countRows(t, db, "images"); count != 2 {
+		t.Errorf("unused images were not removed: %v images", count)
+	}
+
+	expected := map[tiles.TileID]string{
+		{Zoom: 1, X: 0, Y: 0}: "old",
+		{Zoom: 2, X: 1, Y: 0}: "new",
+		{Zoom: 3, X: 0, Y: 0}: "new",
+	}
+	for tileID, value := range expected {
+		if data := readTile(t, db, &tileID); !bytes.Equal(data, []byte(value)) {
+			t.Errorf("%v: %q not expected value: %q", tileID, data, value)
+		}
+	}
+	if data := readTile(t, db, tiles.NewTileID(2, 2, 0)); data != nil {
+		t.Errorf("deleted tile should not be present")
+	}
+
+	metadata, err := db.ReadMetadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata["minzoom"] != "1" || metadata["maxzoom"] != "3" || metadata["bounds"] != "-10.00000,-5.00000,20.00000,10.00000" {
+		t.Errorf("metadata not merged: %v", metadata)
+	}
+}
@@ -0,0 +1,130 @@
+package mbtiles
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"github.com/brendan-ward/rastertiler/tiles"
+)
+
+type MBtilesReader struct {
+	pool *sqlitex.Pool
+}
+
+// Open an existing MBTiles file for reading tiles and metadata
+func NewMBtilesReader(path string, poolsize int) (*MBtilesReader, error) {
+	ext := filepath.Ext(path)
+	if ext != ".mbtiles" {
+		return nil, fmt.Errorf("path must end in .mbtiles")
+	}
+
+	pool, err := sqlitex.Open(path, sqlite.SQLITE_OPEN_READONLY|sqlite.SQLITE_OPEN_NOMUTEX, poolsize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MBtilesReader{
+		pool: pool,
+	}, nil
+}
+
+func (db *MBtilesReader) Close() {
+	if db.pool != nil {
+		db.pool.Close()
+		db.pool = nil
+	}
+}
+
+// GetConnection gets a sqlite.Conn from an open connection pool.
+// CloseConnection(con) must be called to release the connection.
+func (db *MBtilesReader) GetConnection() (*sqlite.Conn, error) {
+	con := db.pool.Get(emptyContext)
+	if con == nil {
+		return nil, fmt.Errorf("connection could not be opened")
+	}
+	return con, nil
+}
+
+// CloseConnection closes an open sqlite.Conn and returns it to the pool.
+func (db *MBtilesReader) CloseConnection(con *sqlite.Conn) {
+	if con != nil {
+		db.pool.Put(con)
+	}
+}
+
+// Read all metadata items as a map of name to value
+func (db *MBtilesReader) ReadMetadata() (map[string]string, error) {
+	if db == nil || db.pool == nil {
+		return nil, fmt.Errorf("cannot read from closed mbtiles database")
+	}
+
+	con, err := db.GetConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.CloseConnection(con)
+
+	return readMetadata(con)
+}
+
+// Read the tile data and SHA-1 tile_id of the tile.  Returns nil data if the
+// tile is not present.
+func (db *MBtilesReader) ReadTile(tile *tiles.TileID) (data []byte, id string, err error) {
+	if db == nil || db.pool == nil {
+		return nil, "", fmt.Errorf("cannot read from closed mbtiles database")
+	}
+
+	con, err := db.GetConnection()
+	if err != nil {
+		return nil, "", err
+	}
+	defer db.CloseConnection(con)
+
+	// flip tile Y to match mbtiles spec
+	y := (1 << tile.Zoom) - 1 - tile.Y
+
+	err = sqlitex.Exec(con, "SELECT images.tile_data, images.tile_id FROM map JOIN images ON images.tile_id = map.tile_id WHERE zoom_level = ? AND tile_column = ? AND tile_row = ?",
+		func(stmt *sqlite.Stmt) error {
+			data = make([]byte, stmt.ColumnLen(0))
+			stmt.ColumnBytes(0, data)
+			id = stmt.ColumnText(1)
+			return nil
+		}, tile.Zoom, tile.X, y)
+	if err != nil {
+		return nil, "", fmt.Errorf("could not read tile %v from mbtiles: %q", tile, err)
+	}
+
+	return data, id, nil
+}
+
+// Read all tiles, calling fn with each tile and its data.  Tiles are read
+// from the tiles table or view, so this supports mbtiles files that were not
+// created by MBtilesWriter.
+func (db *MBtilesReader) ReadTiles(fn func(tile *tiles.TileID, data []byte) error) error {
+	if db == nil || db.pool == nil {
+		return fmt.Errorf("cannot read from closed mbtiles database")
+	}
+
+	con, err := db.GetConnection()
+	if err != nil {
+		return err
+	}
+	defer db.CloseConnection(con)
+
+	err = sqlitex.Exec(con, "SELECT zoom_level, tile_column, tile_row, tile_data FROM tiles",
+		func(stmt *sqlite.Stmt) error {
+			zoom := uint8(stmt.ColumnInt64(0))
+			// flip tile Y from mbtiles spec
+			y := uint32((int64(1) << zoom) - 1 - stmt.ColumnInt64(2))
+			data := make([]byte, stmt.ColumnLen(3))
+			stmt.ColumnBytes(3, data)
+			return fn(tiles.NewTileID(zoom, uint32(stmt.ColumnInt64(1)), y), data)
+		})
+	if err != nil {
+		return fmt.Errorf("could not read tiles from mbtiles: %q", err)
+	}
+
+	return nil
+}
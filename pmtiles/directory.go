@@ -0,0 +1,108 @@
+package pmtiles
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+)
+
+// max size of header and root directory together
+const maxRootSize = 16384
+
+// Entry in a PMTiles directory.  RunLength is 0 for entries that point to
+// leaf directories.
+type Entry struct {
+	TileID    uint64
+	Offset    uint64
+	Length    uint32
+	RunLength uint32
+}
+
+// Serialize directory entries, which must be sorted by TileID, and compress
+// with gzip
+func serializeEntries(entries []Entry) []byte {
+	var b bytes.Buffer
+	buf := make([]byte, binary.MaxVarintLen64)
+
+	writeVarint := func(value uint64) {
+		n := binary.PutUvarint(buf, value)
+		b.Write(buf[:n])
+	}
+
+	writeVarint(uint64(len(entries)))
+
+	var lastID uint64
+	for _, entry := range entries {
+		writeVarint(entry.TileID - lastID)
+		lastID = entry.TileID
+	}
+	for _, entry := range entries {
+		writeVarint(uint64(entry.RunLength))
+	}
+	for _, entry := range entries {
+		writeVarint(uint64(entry.Length))
+	}
+	for i, entry := range entries {
+		// offsets of contiguous entries are stored as 0
+		if i > 0 && entry.Offset == entries[i-1].Offset+uint64(entries[i-1].Length) {
+			writeVarint(0)
+		} else {
+			writeVarint(entry.Offset + 1)
+		}
+	}
+
+	return gzipBytes(b.Bytes())
+}
+
+func gzipBytes(data []byte) []byte {
+	var b bytes.Buffer
+	w, _ := gzip.NewWriterLevel(&b, gzip.BestCompression)
+	w.Write(data)
+	w.Close()
+	return b.Bytes()
+}
+
+// Split entries into leaf directories of leafSize entries, and a root
+// directory that points to the leaf directories
+func buildRootAndLeaves(entries []Entry, leafSize int) (root []byte, leaves []byte, numLeaves int) {
+	rootEntries := make([]Entry, 0, len(entries)/leafSize+1)
+	var leafBuffer bytes.Buffer
+
+	for i := 0; i < len(entries); i += leafSize {
+		end := i + leafSize
+		if end > len(entries) {
+			end = len(entries)
+		}
+		leaf := serializeEntries(entries[i:end])
+		rootEntries = append(rootEntries, Entry{
+			TileID: entries[i].TileID,
+			Offset: uint64(leafBuffer.Len()),
+			Length: uint32(len(leaf)),
+		})
+		leafBuffer.Write(leaf)
+	}
+
+	return serializeEntries(rootEntries), leafBuffer.Bytes(), len(rootEntries)
+}
+
+// Build the root directory and any leaf directories so that the root
+// directory fits within the first 16 KB of the archive along with the header
+func buildDirectories(entries []Entry) (root []byte, leaves []byte, numLeaves int) {
+	targetSize := maxRootSize - headerSize
+
+	if len(entries) < 16384 {
+		root = serializeEntries(entries)
+		if len(root) <= targetSize {
+			return root, nil, 0
+		}
+	}
+
+	leafSize := 4096
+	for {
+		root, leaves, numLeaves = buildRootAndLeaves(entries, leafSize)
+		if len(root) <= targetSize {
+			return root, leaves, numLeaves
+		}
+		leafSize *= 2
+	}
+}
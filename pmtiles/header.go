@@ -0,0 +1,88 @@
+package pmtiles
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+const headerSize = 127
+
+const (
+	compressionNone uint8 = 1
+	compressionGzip uint8 = 2
+)
+
+// mapping of tile format in metadata to PMTiles tile type; other formats,
+// including mixed jpg+png, are unknown (0)
+var tileTypes = map[string]uint8{
+	"mvt":  1,
+	"png":  2,
+	"jpg":  3,
+	"jpeg": 3,
+	"webp": 4,
+}
+
+type header struct {
+	rootOffset          uint64
+	rootLength          uint64
+	metadataOffset      uint64
+	metadataLength      uint64
+	leafOffset          uint64
+	leafLength          uint64
+	tileDataOffset      uint64
+	tileDataLength      uint64
+	addressedTilesCount uint64
+	tileEntriesCount    uint64
+	tileContentsCount   uint64
+	clustered           bool
+	internalCompression uint8
+	tileCompression     uint8
+	tileType            uint8
+	minZoom             uint8
+	maxZoom             uint8
+	minLon              float64
+	minLat              float64
+	maxLon              float64
+	maxLat              float64
+	centerZoom          uint8
+	centerLon           float64
+	centerLat           float64
+}
+
+// coordinates are stored as integers of degrees * 10,000,000
+func toE7(value float64) uint32 {
+	return uint32(int32(math.Round(value * 10000000)))
+}
+
+func (h *header) serialize() []byte {
+	b := make([]byte, headerSize)
+	copy(b[0:7], "PMTiles")
+	b[7] = 3
+	binary.LittleEndian.PutUint64(b[8:], h.rootOffset)
+	binary.LittleEndian.PutUint64(b[16:], h.rootLength)
+	binary.LittleEndian.PutUint64(b[24:], h.metadataOffset)
+	binary.LittleEndian.PutUint64(b[32:], h.metadataLength)
+	binary.LittleEndian.PutUint64(b[40:], h.leafOffset)
+	binary.LittleEndian.PutUint64(b[48:], h.leafLength)
+	binary.LittleEndian.PutUint64(b[56:], h.tileDataOffset)
+	binary.LittleEndian.PutUint64(b[64:], h.tileDataLength)
+	binary.LittleEndian.PutUint64(b[72:], h.addressedTilesCount)
+	binary.LittleEndian.PutUint64(b[80:], h.tileEntriesCount)
+	binary.LittleEndian.PutUint64(b[88:], h.tileContentsCount)
+	if h.clustered {
+		b[96] = 1
+	}
+	b[97] = h.internalCompression
+	b[98] = h.tileCompression
+	b[99] = h.tileType
+	b[100] = h.minZoom
+	b[101] = h.maxZoom
+	binary.LittleEndian.PutUint32(b[102:], toE7(h.minLon))
+	binary.LittleEndian.PutUint32(b[106:], toE7(h.minLat))
+	binary.LittleEndian.PutUint32(b[110:], toE7(h.maxLon))
+	binary.LittleEndian.PutUint32(b[114:], toE7(h.maxLat))
+	b[118] = h.centerZoom
+	binary.LittleEndian.PutUint32(b[119:], toE7(h.centerLon))
+	binary.LittleEndian.PutUint32(b[123:], toE7(h.centerLat))
+	return b
+}
@@ -0,0 +1,251 @@
+package pmtiles
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/brendan-ward/rastertiler/affine"
+	"github.com/brendan-ward/rastertiler/tilejson"
+	"github.com/brendan-ward/rastertiler/tiles"
+)
+
+// unique tile contents, stored in temporary file until archive is written
+type tileContent struct {
+	offset      uint64 // offset in temporary file
+	length      uint32
+	finalOffset uint64 // offset in tile data section of archive
+	assigned    bool
+}
+
+type tileEntry struct {
+	id   uint64
+	hash string
+}
+
+// PMTilesWriter writes a PMTiles v3 archive.  Tiles are deduplicated and
+// stored in a temporary file alongside the output until Finalize is called,
+// which writes the archive with tile data ordered by tile ID.
+type PMTilesWriter struct {
+	path string
+
+	mu       sync.Mutex
+	tmp      *os.File
+	tmpSize  uint64
+	contents map[string]*tileContent // SHA-1 hash of tile data to contents
+	entries  []tileEntry
+	metadata map[string]string
+}
+
+// Create a new PMTilesWriter, overwriting any existing file at path
+func NewPMTilesWriter(path string) (*PMTilesWriter, error) {
+	ext := filepath.Ext(path)
+	if ext != ".pmtiles" {
+		return nil, fmt.Errorf("path must end in .pmtiles")
+	}
+
+	// always overwrite
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		os.Remove(path)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("could not create temporary file: %q", err)
+	}
+
+	return &PMTilesWriter{
+		path:     path,
+		tmp:      tmp,
+		contents: make(map[string]*tileContent),
+		metadata: make(map[string]string),
+	}, nil
+}
+
+// Close and remove temporary file
+func (w *PMTilesWriter) Close() {
+	if w.tmp != nil {
+		w.tmp.Close()
+		os.Remove(w.tmp.Name())
+		w.tmp = nil
+	}
+}
+
+func (w *PMTilesWriter) WriteMetadata(name string, description string, attribution string, minZoom uint8, maxZoom uint8, bounds *affine.Bounds, format string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for key, value := range tilejson.NewMetadata(name, description, attribution, minZoom, maxZoom, bounds, format) {
+		w.metadata[key] = value
+	}
+	return nil
+}
+
+func (w *PMTilesWriter) WriteMetadataItem(key string, value interface{}) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.metadata[key] = fmt.Sprint(value)
+	return nil
+}
+
+// Write the tile; tile data are deduplicated by SHA-1 hash
+func (w *PMTilesWriter) WriteTile(tile *tiles.TileID, data []byte) error {
+	h := sha1.New()
+	h.Write(data)
+	hash := hex.EncodeToString(h.Sum(nil))
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.tmp == nil {
+		return fmt.Errorf("cannot write to closed pmtiles archive")
+	}
+
+	if _, ok := w.contents[hash]; !ok {
+		if _, err := w.tmp.Write(data); err != nil {
+			return fmt.Errorf("could not write tile %v to pmtiles: %q", tile, err)
+		}
+		w.contents[hash] = &tileContent{
+			offset: w.tmpSize,
+			length: uint32(len(data)),
+		}
+		w.tmpSize += uint64(len(data))
+	}
+
+	w.entries = append(w.entries, tileEntry{id: TileIDToPMTilesID(tile), hash: hash})
+
+	return nil
+}
+
+// Write the PMTiles archive from all tiles and metadata that were written
+func (w *PMTilesWriter) Finalize() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.tmp == nil {
+		return fmt.Errorf("cannot write to closed pmtiles archive")
+	}
+
+	sort.Slice(w.entries, func(i, j int) bool {
+		return w.entries[i].id < w.entries[j].id
+	})
+
+	// assign tile data offsets in order of tile ID, and combine consecutive
+	// tiles with the same contents into a single entry
+	entries := make([]Entry, 0, len(w.entries))
+	order := make([]*tileContent, 0, len(w.contents))
+	var tileDataLength uint64
+	for _, e := range w.entries {
+		content := w.contents[e.hash]
+		if !content.assigned {
+			content.finalOffset = tileDataLength
+			content.assigned = true
+			tileDataLength += uint64(content.length)
+			order = append(order, content)
+		}
+
+		if n := len(entries); n > 0 {
+			last := &entries[n-1]
+			if last.Offset == content.finalOffset && e.id == last.TileID+uint64(last.RunLength) {
+				last.RunLength++
+				continue
+			}
+		}
+
+		entries = append(entries, Entry{
+			TileID:    e.id,
+			Offset:    content.finalOffset,
+			Length:    content.length,
+			RunLength: 1,
+		})
+	}
+
+	root, leaves, _ := buildDirectories(entries)
+
+	metadataJSON, err := json.Marshal(w.metadata)
+	if err != nil {
+		return err
+	}
+	metadata := gzipBytes(metadataJSON)
+
+	tj := tilejson.FromMetadata(w.metadata)
+	bounds := tj.Bounds
+	if bounds == nil {
+		bounds = []float64{-180, -85.051129, 180, 85.051129}
+	}
+	center := tj.Center
+	if center == nil {
+		center = []float64{(bounds[0] + bounds[2]) / 2.0, (bounds[1] + bounds[3]) / 2.0, float64(tj.MinZoom)}
+	}
+	format := w.metadata["format"]
+	if format == "" {
+		format = "png"
+	}
+
+	h := header{
+		rootOffset:          headerSize,
+		rootLength:          uint64(len(root)),
+		metadataOffset:      headerSize + uint64(len(root)),
+		metadataLength:      uint64(len(metadata)),
+		leafOffset:          headerSize + uint64(len(root)) + uint64(len(metadata)),
+		leafLength:          uint64(len(leaves)),
+		tileDataLength:      tileDataLength,
+		addressedTilesCount: uint64(len(w.entries)),
+		tileEntriesCount:    uint64(len(entries)),
+		tileContentsCount:   uint64(len(order)),
+		clustered:           true,
+		internalCompression: compressionGzip,
+		tileCompression:     compressionNone,
+		tileType:            tileTypes[format],
+		minZoom:             tj.MinZoom,
+		maxZoom:             tj.MaxZoom,
+		minLon:              bounds[0],
+		minLat:              bounds[1],
+		maxLon:              bounds[2],
+		maxLat:              bounds[3],
+		centerZoom:          uint8(center[2]),
+		centerLon:           center[0],
+		centerLat:           center[1],
+	}
+	h.tileDataOffset = h.leafOffset + h.leafLength
+
+	f, err := os.Create(w.path)
+	if err != nil {
+		return fmt.Errorf("could not create pmtiles archive: %q", err)
+	}
+	defer f.Close()
+
+	out := bufio.NewWriter(f)
+	for _, section := range [][]byte{h.serialize(), root, metadata, leaves} {
+		if _, err = out.Write(section); err != nil {
+			return fmt.Errorf("could not write pmtiles archive: %q", err)
+		}
+	}
+
+	var buffer []byte
+	for _, content := range order {
+		if cap(buffer) < int(content.length) {
+			buffer = make([]byte, content.length)
+		}
+		buffer = buffer[:content.length]
+		if _, err = w.tmp.ReadAt(buffer, int64(content.offset)); err != nil {
+			return fmt.Errorf("could not read tile data: %q", err)
+		}
+		if _, err = out.Write(buffer); err != nil {
+			return fmt.Errorf("could not write pmtiles archive: %q", err)
+		}
+	}
+
+	if err = out.Flush(); err != nil {
+		return fmt.Errorf("could not write pmtiles archive: %q", err)
+	}
+
+	return nil
+}
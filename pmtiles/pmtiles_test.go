@@ -0,0 +1,166 @@
+package pmtiles
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/brendan-ward/rastertiler/affine"
+	"github.com/brendan-ward/rastertiler/tiles"
+)
+
+func gunzip(t *testing.T, data []byte) []byte {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func deserializeEntries(t *testing.T, data []byte) []Entry {
+	r := bytes.NewReader(gunzip(t, data))
+	readVarint := func() uint64 {
+		value, err := binary.ReadUvarint(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return value
+	}
+
+	entries := make([]Entry, readVarint())
+	var lastID uint64
+	for i := range entries {
+		lastID += readVarint()
+		entries[i].TileID = lastID
+	}
+	for i := range entries {
+		entries[i].RunLength = uint32(readVarint())
+	}
+	for i := range entries {
+		entries[i].Length = uint32(readVarint())
+	}
+	for i := range entries {
+		offset := readVarint()
+		if offset == 0 && i > 0 {
+			entries[i].Offset = entries[i-1].Offset + uint64(entries[i-1].Length)
+		} else {
+			entries[i].Offset = offset - 1
+		}
+	}
+	return entries
+}
+
+// find entry that contains id, following leaf directories as needed
+func findTile(t *testing.T, archive []byte, dirOffset uint64, dirLength uint64, leafOffset uint64, id uint64) (Entry, bool) {
+	entries := deserializeEntries(t, archive[dirOffset:dirOffset+dirLength])
+	i := sort.Search(len(entries), func(i int) bool { return entries[i].TileID > id }) - 1
+	if i < 0 {
+		return Entry{}, false
+	}
+	entry := entries[i]
+	if entry.RunLength == 0 {
+		return findTile(t, archive, leafOffset+entry.Offset, uint64(entry.Length), leafOffset, id)
+	}
+	if id < entry.TileID+uint64(entry.RunLength) {
+		return entry, true
+	}
+	return Entry{}, false
+}
+
+func TestPMTilesWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.pmtiles")
+	w, err := NewPMTilesWriter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if err = w.WriteMetadata("test", "", "", 0, 7, &affine.Bounds{Xmin: -100, Ymin: 30, Xmax: -90, Ymax: 40}, "png"); err != nil {
+		t.Fatal(err)
+	}
+
+	// write enough unique tiles to require leaf directories, and some that
+	// are duplicates
+	expected := make(map[tiles.TileID]string)
+	for z := uint8(0); z <= 7; z++ {
+		for x := uint32(0); x < 1<<z; x++ {
+			for y := uint32(0); y < 1<<z; y++ {
+				data := fmt.Sprintf("%v/%v/%v", z, x, y)
+				if x == 0 {
+					data = "blank"
+				}
+				expected[tiles.TileID{Zoom: z, X: x, Y: y}] = data
+				if err = w.WriteTile(tiles.NewTileID(z, x, y), []byte(data)); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+
+	if err = w.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(archive[0:7]) != "PMTiles" || archive[7] != 3 {
+		t.Fatalf("invalid header")
+	}
+	rootOffset := binary.LittleEndian.Uint64(archive[8:])
+	rootLength := binary.LittleEndian.Uint64(archive[16:])
+	metadataOffset := binary.LittleEndian.Uint64(archive[24:])
+	metadataLength := binary.LittleEndian.Uint64(archive[32:])
+	leafOffset := binary.LittleEndian.Uint64(archive[40:])
+	leafLength := binary.LittleEndian.Uint64(archive[48:])
+	tileDataOffset := binary.LittleEndian.Uint64(archive[56:])
+
+	if rootOffset+rootLength > maxRootSize {
+		t.Errorf("root directory is too large: %v", rootLength)
+	}
+	if leafLength == 0 {
+		t.Errorf("leaf directories should have been created")
+	}
+	if archive[100] != 0 || archive[101] != 7 || archive[99] != tileTypes["png"] {
+		t.Errorf("header zoom or tile type not expected values")
+	}
+	if minLon := int32(binary.LittleEndian.Uint32(archive[102:])); minLon != -1000000000 {
+		t.Errorf("min lon %v not expected value", minLon)
+	}
+	if addressed := binary.LittleEndian.Uint64(archive[72:]); addressed != uint64(len(expected)) {
+		t.Errorf("addressed tiles %v not expected value: %v", addressed, len(expected))
+	}
+
+	var metadata map[string]string
+	if err = json.Unmarshal(gunzip(t, archive[metadataOffset:metadataOffset+metadataLength]), &metadata); err != nil {
+		t.Fatal(err)
+	}
+	if metadata["name"] != "test" {
+		t.Errorf("metadata %v not expected value", metadata)
+	}
+
+	for tileID, data := range expected {
+		entry, ok := findTile(t, archive, rootOffset, rootLength, leafOffset, TileIDToPMTilesID(&tileID))
+		if !ok {
+			t.Fatalf("tile %v not found", tileID)
+		}
+		start := tileDataOffset + entry.Offset
+		value := string(archive[start : start+uint64(entry.Length)])
+		if value != data {
+			t.Errorf("tile %v: %v not expected value: %v", tileID, value, data)
+		}
+	}
+}
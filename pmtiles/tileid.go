@@ -0,0 +1,44 @@
+package pmtiles
+
+import (
+	"github.com/brendan-ward/rastertiler/tiles"
+)
+
+func rotate(n uint32, x *uint32, y *uint32, rx uint32, ry uint32) {
+	if ry == 0 {
+		if rx == 1 {
+			*x = n - 1 - *x
+			*y = n - 1 - *y
+		}
+		*x, *y = *y, *x
+	}
+}
+
+// Convert tile zoom, x, y to a PMTiles tile ID, which orders tiles by zoom
+// and then along a Hilbert curve within each zoom level
+func TileIDToPMTilesID(tile *tiles.TileID) uint64 {
+	var acc uint64
+	for z := uint8(0); z < tile.Zoom; z++ {
+		acc += (uint64(1) << z) * (uint64(1) << z)
+	}
+
+	n := uint32(1) << tile.Zoom
+	var rx uint32
+	var ry uint32
+	var d uint64
+	x := tile.X
+	y := tile.Y
+	for s := n / 2; s > 0; s /= 2 {
+		rx = 0
+		if x&s > 0 {
+			rx = 1
+		}
+		ry = 0
+		if y&s > 0 {
+			ry = 1
+		}
+		d += uint64(s) * uint64(s) * uint64((3*rx)^ry)
+		rotate(s, &x, &y, rx, ry)
+	}
+	return acc + d
+}
@@ -0,0 +1,31 @@
+package pmtiles
+
+import (
+	"testing"
+
+	"github.com/brendan-ward/rastertiler/tiles"
+)
+
+func TestTileIDToPMTilesID(t *testing.T) {
+	tests := []struct {
+		tile     *tiles.TileID
+		expected uint64
+	}{
+		{tile: tiles.NewTileID(0, 0, 0), expected: 0},
+		{tile: tiles.NewTileID(1, 0, 0), expected: 1},
+		{tile: tiles.NewTileID(1, 0, 1), expected: 2},
+		{tile: tiles.NewTileID(1, 1, 1), expected: 3},
+		{tile: tiles.NewTileID(1, 1, 0), expected: 4},
+		{tile: tiles.NewTileID(2, 0, 0), expected: 5},
+		{tile: tiles.NewTileID(3, 0, 0), expected: 21},
+		{tile: tiles.NewTileID(3, 7, 0), expected: 84},
+		{tile: tiles.NewTileID(20, 0, 0), expected: 366503875925},
+	}
+
+	for _, tc := range tests {
+		id := TileIDToPMTilesID(tc.tile)
+		if id != tc.expected {
+			t.Errorf("%v: %v not expected value: %v", tc.tile, id, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"container/list"
+	"sync"
+)
+
+type cacheEntry struct {
+	key  string
+	data []byte
+	id   string
+}
+
+// tileCache is a least-recently-used cache of encoded tiles, limited by the
+// total size in bytes of the tiles
+type tileCache struct {
+	mu      sync.Mutex
+	maxSize int
+	size    int
+	order   *list.List // most recently used at front
+	entries map[string]*list.Element
+}
+
+func newTileCache(maxSize int) *tileCache {
+	return &tileCache{
+		maxSize: maxSize,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Get tile data and id from cache; ok is false if tile is not in cache
+func (c *tileCache) Get(key string) (data []byte, id string, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, "", false
+	}
+	c.order.MoveToFront(element)
+	entry := element.Value.(*cacheEntry)
+	return entry.data, entry.id, true
+}
+
+// Add tile data and id to cache, removing least recently used tiles as
+// needed to stay within the size limit.  Tiles without data are cached so
+// that they do not need to be read again.
+func (c *tileCache) Add(key string, data []byte, id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	size := entrySize(key, data)
+	if size > c.maxSize {
+		return
+	}
+
+	if element, ok := c.entries[key]; ok {
+		c.size -= entrySize(key, element.Value.(*cacheEntry).data)
+		c.order.Remove(element)
+		delete(c.entries, key)
+	}
+
+	for c.size+size > c.maxSize {
+		oldest := c.order.Back()
+		entry := oldest.Value.(*cacheEntry)
+		c.size -= entrySize(entry.key, entry.data)
+		c.order.Remove(oldest)
+		delete(c.entries, entry.key)
+	}
+
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, data: data, id: id})
+	c.size += size
+}
+
+func (c *tileCache) Size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.size
+}
+
+func entrySize(key string, data []byte) int {
+	return len(key) + len(data)
+}
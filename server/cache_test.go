@@ -0,0 +1,39 @@
+package server
+
+import (
+	"testing"
+)
+
+func TestTileCache(t *testing.T) {
+	cache := newTileCache(20)
+
+	cache.Add("a", []byte("1234"), "a1") // size 5
+	cache.Add("b", []byte("1234"), "b1") // size 5
+	cache.Add("c", nil, "")              // size 1
+
+	if _, _, ok := cache.Get("a"); !ok {
+		t.Errorf("tile a should be in cache")
+	}
+	data, _, ok := cache.Get("c")
+	if !ok || data != nil {
+		t.Errorf("empty tile c should be in cache")
+	}
+
+	// b is least recently used and should be removed to make room
+	cache.Add("d", []byte("1234567890"), "d1") // size 11
+	if _, _, ok := cache.Get("b"); ok {
+		t.Errorf("tile b should have been removed from cache")
+	}
+	if data, id, ok := cache.Get("d"); !ok || string(data) != "1234567890" || id != "d1" {
+		t.Errorf("tile d should be in cache")
+	}
+	if cache.Size() != 17 {
+		t.Errorf("cache size %v not expected value: 17", cache.Size())
+	}
+
+	// tiles larger than cache are not added
+	cache.Add("e", make([]byte, 100), "e1")
+	if _, _, ok := cache.Get("e"); ok {
+		t.Errorf("tile e should not be in cache")
+	}
+}
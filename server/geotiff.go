@@ -0,0 +1,260 @@
+package server
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/brendan-ward/rastertiler/affine"
+	"github.com/brendan-ward/rastertiler/array"
+	"github.com/brendan-ward/rastertiler/encoding"
+	"github.com/brendan-ward/rastertiler/gdal"
+	"github.com/brendan-ward/rastertiler/tiles"
+)
+
+const minTileSize = 64
+const maxTileSize = 4096
+
+// warped VRT of a dataset; GDAL datasets cannot be shared between goroutines
+// so each is used by only one request at a time
+type warpedVRT struct {
+	ds  *gdal.Dataset
+	vrt *gdal.Dataset
+}
+
+// geotiffTileset renders tiles on demand from a GeoTIFF
+type geotiffTileset struct {
+	name       string
+	dtype      string // dtype of tiles after rescaling, unless using a gradient
+	rawDtype   string // dtype of GeoTIFF
+	bands      int
+	nodata     interface{}
+	rescale    bool // rescale float data to uint8 from rescaleMin to rescaleMax if not using a gradient
+	rescaleMin float64
+	rescaleMax float64
+	metadata   map[string]string
+	tileSize   int
+	colormap   string
+	colorTable *encoding.Colormap // used if colormap is not provided
+	pool       chan *warpedVRT
+	cache      *tileCache
+}
+
+// Add a GeoTIFF to the server that is rendered to tiles on demand, using up
+// to poolsize warped VRTs concurrently.  The tileset is named from the
+// filename without extension.  TileSize and colormap are the defaults used
+// when not provided as query parameters; if colormap is empty, the color
+// table of the GeoTIFF is used if present.
+func (s *Server) AddGeoTIFF(filename string, poolsize int, tileSize int, colormap string, minZoom uint8, maxZoom uint8) error {
+	ts, err := newGeoTIFFTileset(filename, poolsize, tileSize, colormap, minZoom, maxZoom, s.cache)
+	if err != nil {
+		return err
+	}
+	return s.addTileset(ts.name, ts)
+}
+
+func newGeoTIFFTileset(filename string, poolsize int, tileSize int, colormap string, minZoom uint8, maxZoom uint8, cache *tileCache) (*geotiffTileset, error) {
+	name := tilesetName(filename)
+
+	d, err := gdal.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+
+	geoBounds, err := d.GeoBounds()
+	if err != nil {
+		return nil, err
+	}
+
+	ts := &geotiffTileset{
+		name:     name,
+		dtype:    d.DType(),
+		rawDtype: d.DType(),
+		bands:    d.BandCount(),
+		nodata:   d.Nodata(),
+		metadata: map[string]string{
+			"name":    name,
+			"minzoom": fmt.Sprint(minZoom),
+			"maxzoom": fmt.Sprint(maxZoom),
+			"bounds":  fmt.Sprintf("%.5f,%.5f,%.5f,%.5f", geoBounds.Xmin, geoBounds.Ymin, geoBounds.Xmax, geoBounds.Ymax),
+			"center":  fmt.Sprintf("%.5f,%.5f,%v", (geoBounds.Xmin+geoBounds.Xmax)/2.0, (geoBounds.Ymin+geoBounds.Ymax)/2.0, minZoom),
+			"format":  "png",
+		},
+		tileSize: tileSize,
+		colormap: colormap,
+		pool:     make(chan *warpedVRT, poolsize),
+		cache:    cache,
+	}
+
+	if ts.bands == 1 && (ts.dtype == "uint8" || ts.dtype == "uint16") {
+		colors, labels, err := d.ColorTable()
+		if err != nil {
+			return nil, err
+		}
+		if colors != nil {
+			if ts.colorTable, err = encoding.NewColormapFromColorTable(colors, labels); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// float data are rescaled to uint8 using the range of the data
+	if ts.dtype == "float32" || ts.dtype == "float64" {
+		ts.rescaleMin, ts.rescaleMax, err = d.Statistics(true)
+		if err != nil {
+			return nil, err
+		}
+		if ts.rescaleMax <= ts.rescaleMin {
+			ts.rescaleMax = ts.rescaleMin + 1
+		}
+		ts.rescale = true
+		ts.dtype = "uint8"
+	}
+
+	// validate default options
+	if _, _, err = ts.options(url.Values{}); err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < poolsize; i++ {
+		ds, err := gdal.Open(filename)
+		if err != nil {
+			ts.Close()
+			return nil, err
+		}
+		vrt, err := ds.GetWarpedVRT(tiles.WebMercatorQuad.CRS, gdal.Nearest, nil)
+		if err != nil {
+			ds.Close()
+			ts.Close()
+			return nil, err
+		}
+		ts.pool <- &warpedVRT{ds: ds, vrt: vrt}
+	}
+
+	return ts, nil
+}
+
+func (ts *geotiffTileset) Metadata() map[string]string {
+	return ts.metadata
+}
+
+// Get tile size and colormap from query parameters, falling back to defaults
+func (ts *geotiffTileset) options(query url.Values) (tileSize int, colormap *encoding.Colormap, err error) {
+	tileSize = ts.tileSize
+	if value := query.Get("tilesize"); value != "" {
+		tileSize, err = strconv.Atoi(value)
+		if err != nil {
+			return 0, nil, &RequestError{fmt.Sprintf("invalid tilesize: %v", value)}
+		}
+	}
+	if tileSize < minTileSize || tileSize > maxTileSize {
+		return 0, nil, &RequestError{fmt.Sprintf("tilesize must be between %v and %v", minTileSize, maxTileSize)}
+	}
+
+	colormapStr := ts.colormap
+	if value := query.Get("colormap"); value != "" {
+		colormapStr = value
+	}
+	if colormapStr != "" {
+		if ts.bands != 1 {
+			return 0, nil, &RequestError{"colormap is only valid for single-band data"}
+		}
+		colormap, err = encoding.NewColormap(colormapStr)
+		if err != nil {
+			return 0, nil, &RequestError{fmt.Sprintf("invalid colormap: %v", err)}
+		}
+		if colormap.Gradient() == nil && (ts.dtype == "float32" || ts.dtype == "float64") {
+			return 0, nil, &RequestError{"categorical colormap is only valid for integer data"}
+		}
+	} else {
+		colormap = ts.colorTable
+	}
+
+	return tileSize, colormap, nil
+}
+
+func (ts *geotiffTileset) ReadTile(tileID *tiles.TileID, query url.Values) ([]byte, string, error) {
+	tileSize, colormap, err := ts.options(query)
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := fmt.Sprintf("%s/%v/%v/%v?tilesize=%v&colormap=%s", ts.name, tileID.Zoom, tileID.X, tileID.Y, tileSize, strings.ReplaceAll(query.Get("colormap"), " ", ""))
+	if data, id, ok := ts.cache.Get(key); ok {
+		return data, id, nil
+	}
+
+	data, err := ts.renderTile(tileID, tileSize, colormap)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var id string
+	if data != nil {
+		h := sha1.New()
+		h.Write(data)
+		id = hex.EncodeToString(h.Sum(nil))
+	}
+
+	ts.cache.Add(key, data, id)
+
+	return data, id, nil
+}
+
+// Render the tile using a warped VRT from the pool; returns nil if the tile
+// does not contain data
+func (ts *geotiffTileset) renderTile(tileID *tiles.TileID, tileSize int, colormap *encoding.Colormap) ([]byte, error) {
+	var tileTransform affine.Affine
+
+	// gradients are applied to raw values
+	rescale := ts.rescale && (colormap == nil || colormap.Gradient() == nil)
+	dtype := ts.rawDtype
+	nodata := ts.nodata
+	if rescale {
+		dtype = ts.dtype
+		nodata = array.ZeroValue(dtype)
+	}
+
+	buffer := array.NewBuffer(dtype, tileSize*tileSize*ts.bands)
+	encoder, err := encoding.NewEncoder(dtype, ts.bands, tileSize, tileSize, colormap, nodata)
+	if err != nil {
+		return nil, err
+	}
+
+	readBuffer := buffer
+	if rescale {
+		readBuffer = array.NewBuffer(ts.rawDtype, tileSize*tileSize*ts.bands)
+	}
+
+	w := <-ts.pool
+	mask := make([]uint8, tileSize*tileSize)
+	coverage, err := w.vrt.ReadTile(readBuffer, mask, &tileTransform, tiles.WebMercatorQuad, tileID, tileSize)
+	ts.pool <- w
+	if err != nil || coverage == gdal.NotCovered {
+		return nil, err
+	}
+
+	if rescale {
+		if err = array.Rescale(buffer, readBuffer, ts.rescaleMin, ts.rescaleMax, ts.nodata); err != nil {
+			return nil, err
+		}
+	}
+
+	return encoder.Encode(buffer, mask)
+}
+
+func (ts *geotiffTileset) Close() {
+	for {
+		select {
+		case w := <-ts.pool:
+			w.vrt.Close()
+			w.ds.Close()
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/brendan-ward/rastertiler/mbtiles"
+	"github.com/brendan-ward/rastertiler/tilejson"
+	"github.com/brendan-ward/rastertiler/tiles"
+)
+
+// mapping of tile format in metadata to content type
+var contentTypes = map[string]string{
+	"png":  "image/png",
+	"jpg":  "image/jpeg",
+	"jpeg": "image/jpeg",
+	"webp": "image/webp",
+}
+
+// tileset provides metadata and tiles for a single tileset
+type tileset interface {
+	Metadata() map[string]string
+	// ReadTile returns the encoded tile and an identifier of its contents
+	// used as an ETag, or nil data if the tile does not contain data.
+	// Query contains the request query parameters.
+	ReadTile(tileID *tiles.TileID, query url.Values) (data []byte, id string, err error)
+	Close()
+}
+
+// RequestError indicates that tile request parameters are invalid
+type RequestError struct {
+	msg string
+}
+
+func (e *RequestError) Error() string {
+	return e.msg
+}
+
+type mbtilesTileset struct {
+	db       *mbtiles.MBtilesReader
+	metadata map[string]string
+}
+
+func (ts *mbtilesTileset) Metadata() map[string]string {
+	return ts.metadata
+}
+
+func (ts *mbtilesTileset) ReadTile(tileID *tiles.TileID, query url.Values) ([]byte, string, error) {
+	return ts.db.ReadTile(tileID)
+}
+
+func (ts *mbtilesTileset) Close() {
+	ts.db.Close()
+}
+
+// Server serves tiles and TileJSON from one or more MBTiles files or
+// GeoTIFFs.
+//
+// Routes:
+// /                        list of tileset names
+// /{tileset}.json          TileJSON
+// /{tileset}/{z}/{x}/{y}.png  tile, numbered from upper left; extension is optional
+type Server struct {
+	tilesets map[string]tileset
+	cache    *tileCache
+}
+
+// Create a new Server; cacheSize is the maximum size in bytes of tiles
+// rendered from GeoTIFFs to keep in memory
+func NewServer(cacheSize int) *Server {
+	return &Server{
+		tilesets: make(map[string]tileset),
+		cache:    newTileCache(cacheSize),
+	}
+}
+
+// Get tileset name from filename without extension
+func tilesetName(filename string) string {
+	return strings.TrimSuffix(path.Base(filename), filepath.Ext(filename))
+}
+
+func (s *Server) addTileset(name string, ts tileset) error {
+	if _, ok := s.tilesets[name]; ok {
+		ts.Close()
+		return fmt.Errorf("tileset '%s' has already been added", name)
+	}
+	s.tilesets[name] = ts
+	return nil
+}
+
+// Add an MBTiles file to the server; the tileset is named from the filename
+// without extension
+func (s *Server) AddMBTiles(filename string, poolsize int) error {
+	db, err := mbtiles.NewMBtilesReader(filename, poolsize)
+	if err != nil {
+		return err
+	}
+
+	metadata, err := db.ReadMetadata()
+	if err != nil {
+		db.Close()
+		return err
+	}
+
+	return s.addTileset(tilesetName(filename), &mbtilesTileset{
+		db:       db,
+		metadata: metadata,
+	})
+}
+
+// Names returns the sorted names of all tilesets
+func (s *Server) Names() []string {
+	names := make([]string, 0, len(s.tilesets))
+	for name := range s.tilesets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *Server) Close() {
+	for _, ts := range s.tilesets {
+		ts.Close()
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "":
+		writeJSON(w, s.Names())
+
+	case len(parts) == 1 && strings.HasSuffix(parts[0], ".json"):
+		s.serveTileJSON(w, r, strings.TrimSuffix(parts[0], ".json"))
+
+	case len(parts) == 4:
+		s.serveTile(w, r, parts[0], parts[1], parts[2], parts[3])
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) serveTileJSON(w http.ResponseWriter, r *http.Request, name string) {
+	ts, ok := s.tilesets[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	metadata := ts.Metadata()
+	format := metadata["format"]
+	if format == "" {
+		format = "png"
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	// tiles of mixed formats, e.g., jpg+png, do not have a single extension
+	ext := "." + format
+	if _, ok := contentTypes[format]; !ok {
+		ext = ""
+	}
+	tileURL := fmt.Sprintf("%s://%s/%s/{z}/{x}/{y}%s", scheme, r.Host, name, ext)
+	// pass through query parameters used to render tiles
+	if r.URL.RawQuery != "" {
+		tileURL += "?" + r.URL.RawQuery
+	}
+
+	writeJSON(w, tilejson.FromMetadata(metadata, tileURL))
+}
+
+func (s *Server) serveTile(w http.ResponseWriter, r *http.Request, name string, zStr string, xStr string, yStr string) {
+	ts, ok := s.tilesets[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	metadata := ts.Metadata()
+	tj := tilejson.FromMetadata(metadata)
+	tileID, err := parseTileID(zStr, xStr, yStr)
+	if err != nil || tileID.Zoom < tj.MinZoom || tileID.Zoom > tj.MaxZoom {
+		http.NotFound(w, r)
+		return
+	}
+	if tms := metadataTileMatrixSet(metadata); tms != nil && !tms.Contains(tileID) {
+		http.NotFound(w, r)
+		return
+	}
+
+	data, id, err := ts.ReadTile(tileID, r.URL.Query())
+	if err != nil {
+		var requestErr *RequestError
+		if errors.As(err, &requestErr) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// tiles without data are not written to the tileset
+	if data == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	etag := fmt.Sprintf("\"%s\"", id)
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	// tiles of mixed formats, e.g., jpg+png, are identified from their contents
+	contentType, ok := contentTypes[metadata["format"]]
+	if !ok {
+		contentType = http.DetectContentType(data)
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Write(data)
+}
+
+// Get the tile matrix set of a tileset from its tile_matrix_set metadata
+// item, which is empty for WebMercatorQuad.  Returns nil for tile matrix sets
+// that are not built in, such as those read from a TileMatrixSet JSON file,
+// because their extent is not known.
+func metadataTileMatrixSet(metadata map[string]string) *tiles.TileMatrixSet {
+	id := metadata["tile_matrix_set"]
+	if id == "" {
+		return tiles.WebMercatorQuad
+	}
+	return tiles.TileMatrixSets[id]
+}
+
+// Parse tile zoom, x, y from path parts; y may include a file extension.
+// Whether the tile is within the tile matrix set is checked separately.
+func parseTileID(zStr string, xStr string, yStr string) (*tiles.TileID, error) {
+	if i := strings.Index(yStr, "."); i >= 0 {
+		yStr = yStr[:i]
+	}
+
+	z, err := strconv.ParseUint(zStr, 10, 8)
+	if err != nil {
+		return nil, fmt.Errorf("invalid zoom: %v", zStr)
+	}
+	x, err := strconv.ParseUint(xStr, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tile x: %v", xStr)
+	}
+	y, err := strconv.ParseUint(yStr, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tile y: %v", yStr)
+	}
+
+	return tiles.NewTileID(uint8(z), uint32(x), uint32(y)), nil
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/brendan-ward/rastertiler/tiles"
+)
+
+// memoryTileset returns the same data for every tile
+type memoryTileset struct {
+	metadata map[string]string
+}
+
+func (ts *memoryTileset) Metadata() map[string]string {
+	return ts.metadata
+}
+
+func (ts *memoryTileset) ReadTile(tileID *tiles.TileID, query url.Values) ([]byte, string, error) {
+	return []byte("tile"), tileID.String(), nil
+}
+
+func (ts *memoryTileset) Close() {}
+
+func TestServeTileRange(t *testing.T) {
+	s := NewServer(0)
+	s.addTileset("mercator", &memoryTileset{metadata: map[string]string{"minzoom": "0", "maxzoom": "4", "format": "png"}})
+	s.addTileset("wgs84", &memoryTileset{metadata: map[string]string{"minzoom": "0", "maxzoom": "4", "format": "png", "tile_matrix_set": "WorldCRS84Quad"}})
+	s.addTileset("custom", &memoryTileset{metadata: map[string]string{"minzoom": "0", "maxzoom": "4", "format": "png", "tile_matrix_set": "NZTM2000Quad"}})
+
+	tests := []struct {
+		path     string
+		expected int
+	}{
+		{path: "/mercator/1/1/1.png", expected: http.StatusOK},
+		{path: "/mercator/1/2/1.png", expected: http.StatusNotFound},
+		{path: "/mercator/5/0/0.png", expected: http.StatusNotFound},
+		{path: "/wgs84/0/1/0.png", expected: http.StatusOK},
+		{path: "/wgs84/1/3/1.png", expected: http.StatusOK},
+		{path: "/wgs84/1/4/1.png", expected: http.StatusNotFound},
+		{path: "/wgs84/1/3/2.png", expected: http.StatusNotFound},
+		{path: "/custom/1/5/5.png", expected: http.StatusOK},
+		{path: "/missing/0/0/0.png", expected: http.StatusNotFound},
+	}
+
+	for _, tc := range tests {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if w.Code != tc.expected {
+			t.Errorf("%v: status %v not expected value: %v", tc.path, w.Code, tc.expected)
+		}
+	}
+}
+
+func TestServeTileJSONURL(t *testing.T) {
+	s := NewServer(0)
+	s.addTileset("webp", &memoryTileset{metadata: map[string]string{"minzoom": "0", "maxzoom": "4", "format": "webp"}})
+	s.addTileset("mixed", &memoryTileset{metadata: map[string]string{"minzoom": "0", "maxzoom": "4", "format": "jpg+png"}})
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{name: "webp", expected: "http://example.com/webp/{z}/{x}/{y}.webp"},
+		{name: "mixed", expected: "http://example.com/mixed/{z}/{x}/{y}"},
+	}
+
+	for _, tc := range tests {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://example.com/"+tc.name+".json", nil))
+		var tj struct {
+			Tiles []string `json:"tiles"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&tj); err != nil {
+			t.Fatal(err)
+		}
+		if len(tj.Tiles) != 1 || tj.Tiles[0] != tc.expected {
+			t.Errorf("%v: tiles %v not expected value: %v", tc.name, tj.Tiles, tc.expected)
+		}
+	}
+
+	// tiles without an extension are served
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/mixed/1/0/0", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("tile without extension: status %v not expected value: %v", w.Code, http.StatusOK)
+	}
+}
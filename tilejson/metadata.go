@@ -0,0 +1,56 @@
+package tilejson
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/brendan-ward/rastertiler/affine"
+)
+
+// Create the metadata items for a tileset with tiles of format, e.g., "png",
+// stored as strings in the same form as the mbtiles metadata table
+func NewMetadata(name string, description string, attribution string, minZoom uint8, maxZoom uint8, bounds *affine.Bounds, format string) map[string]string {
+	metadata := map[string]string{
+		"name":    name,
+		"minzoom": fmt.Sprint(minZoom),
+		"maxzoom": fmt.Sprint(maxZoom),
+		"center":  fmt.Sprintf("%.5f,%.5f,%v", (bounds.Xmin+bounds.Xmax)/2.0, (bounds.Ymin+bounds.Ymax)/2.0, minZoom),
+		"bounds":  fmt.Sprintf("%.5f,%.5f,%.5f,%.5f", bounds.Xmin, bounds.Ymin, bounds.Xmax, bounds.Ymax),
+		"type":    "overlay",
+		"format":  format,
+		"version": "1.0.0",
+	}
+	if description != "" {
+		metadata["description"] = description
+	}
+	if attribution != "" {
+		metadata["attribution"] = attribution
+	}
+	return metadata
+}
+
+// Merge zoom levels and bounds with those already present in existing
+// metadata, so that the metadata covers all tiles in the tileset
+func MergeMetadata(existing map[string]string, minZoom uint8, maxZoom uint8, bounds *affine.Bounds) (uint8, uint8, *affine.Bounds) {
+	merged := *bounds
+
+	if value, ok := existing["minzoom"]; ok {
+		if existingZoom, err := strconv.ParseUint(value, 10, 8); err == nil && uint8(existingZoom) < minZoom {
+			minZoom = uint8(existingZoom)
+		}
+	}
+	if value, ok := existing["maxzoom"]; ok {
+		if existingZoom, err := strconv.ParseUint(value, 10, 8); err == nil && uint8(existingZoom) > maxZoom {
+			maxZoom = uint8(existingZoom)
+		}
+	}
+	if values := parseFloats(existing["bounds"], 4); values != nil {
+		merged.Xmin = math.Min(merged.Xmin, values[0])
+		merged.Ymin = math.Min(merged.Ymin, values[1])
+		merged.Xmax = math.Max(merged.Xmax, values[2])
+		merged.Ymax = math.Max(merged.Ymax, values[3])
+	}
+
+	return minZoom, maxZoom, &merged
+}
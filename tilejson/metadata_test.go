@@ -0,0 +1,52 @@
+package tilejson
+
+import (
+	"testing"
+
+	"github.com/brendan-ward/rastertiler/affine"
+)
+
+func TestNewMetadata(t *testing.T) {
+	metadata := NewMetadata("test", "", "", 0, 4, &affine.Bounds{Xmin: -10, Ymin: -20, Xmax: 20, Ymax: 10}, "webp")
+
+	expected := map[string]string{
+		"name":    "test",
+		"minzoom": "0",
+		"maxzoom": "4",
+		"center":  "5.00000,-5.00000,0",
+		"bounds":  "-10.00000,-20.00000,20.00000,10.00000",
+		"format":  "webp",
+	}
+	for key, value := range expected {
+		if metadata[key] != value {
+			t.Errorf("%v: %v not expected value: %v", key, metadata[key], value)
+		}
+	}
+	if _, ok := metadata["description"]; ok {
+		t.Errorf("empty description should be omitted")
+	}
+}
+
+func TestMergeMetadata(t *testing.T) {
+	existing := map[string]string{
+		"minzoom": "0",
+		"maxzoom": "10",
+		"bounds":  "-10.00000,-10.00000,10.00000,10.00000",
+	}
+
+	minZoom, maxZoom, bounds := MergeMetadata(existing, 11, 14, &affine.Bounds{Xmin: 0, Ymin: -20, Xmax: 20, Ymax: 5})
+
+	if minZoom != 0 || maxZoom != 14 {
+		t.Errorf("zoom levels (%v, %v) not expected value: (0, 14)", minZoom, maxZoom)
+	}
+	expected := affine.Bounds{Xmin: -10, Ymin: -20, Xmax: 20, Ymax: 10}
+	if *bounds != expected {
+		t.Errorf("%v not expected value: %v", bounds, expected)
+	}
+
+	// no existing metadata
+	minZoom, maxZoom, bounds = MergeMetadata(map[string]string{}, 11, 14, &expected)
+	if minZoom != 11 || maxZoom != 14 || *bounds != expected {
+		t.Errorf("metadata should not have been modified")
+	}
+}
@@ -0,0 +1,75 @@
+package tilejson
+
+import (
+	"strconv"
+	"strings"
+)
+
+const Version string = "2.2.0"
+
+// TileJSON document describing a tileset, see https://github.com/mapbox/tilejson-spec
+type TileJSON struct {
+	TileJSON      string    `json:"tilejson"`
+	Name          string    `json:"name,omitempty"`
+	Description   string    `json:"description,omitempty"`
+	Attribution   string    `json:"attribution,omitempty"`
+	Version       string    `json:"version,omitempty"`
+	Scheme        string    `json:"scheme"`
+	Tiles         []string  `json:"tiles"`
+	Format        string    `json:"format,omitempty"`
+	MinZoom       uint8     `json:"minzoom"`
+	MaxZoom       uint8     `json:"maxzoom"`
+	Bounds        []float64 `json:"bounds,omitempty"`
+	Center        []float64 `json:"center,omitempty"`
+	Encoding      string    `json:"encoding,omitempty"`        // elevation encoding of raster-dem tiles: mapbox or terrarium
+	TileMatrixSet string    `json:"tile_matrix_set,omitempty"` // ID of tile matrix set of tiles, if not WebMercatorQuad
+	CRS           string    `json:"crs,omitempty"`             // CRS of tile matrix set
+}
+
+// Create TileJSON from mbtiles-style metadata, which stores all values as
+// strings, e.g., bounds as "xmin,ymin,xmax,ymax"
+func FromMetadata(metadata map[string]string, tileURLs ...string) *TileJSON {
+	tj := &TileJSON{
+		TileJSON:      Version,
+		Name:          metadata["name"],
+		Description:   metadata["description"],
+		Attribution:   metadata["attribution"],
+		Version:       metadata["version"],
+		Scheme:        "xyz",
+		Tiles:         tileURLs,
+		Format:        metadata["format"],
+		Bounds:        parseFloats(metadata["bounds"], 4),
+		Center:        parseFloats(metadata["center"], 3),
+		Encoding:      metadata["encoding"],
+		TileMatrixSet: metadata["tile_matrix_set"],
+		CRS:           metadata["crs"],
+	}
+
+	if value, err := strconv.ParseUint(metadata["minzoom"], 10, 8); err == nil {
+		tj.MinZoom = uint8(value)
+	}
+	if value, err := strconv.ParseUint(metadata["maxzoom"], 10, 8); err == nil {
+		tj.MaxZoom = uint8(value)
+	}
+
+	return tj
+}
+
+// Parse a comma-delimited list of exactly size numbers; returns nil if value
+// is empty or invalid
+func parseFloats(value string, size int) []float64 {
+	parts := strings.Split(value, ",")
+	if len(parts) != size {
+		return nil
+	}
+
+	values := make([]float64, size)
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil
+		}
+		values[i] = v
+	}
+	return values
+}
@@ -0,0 +1,61 @@
+package tilejson
+
+import (
+	"testing"
+)
+
+func TestFromMetadata(t *testing.T) {
+	metadata := map[string]string{
+		"name":    "test",
+		"minzoom": "2",
+		"maxzoom": "10",
+		"bounds":  "-10.00000,-20.00000,10.00000,20.00000",
+		"center":  "0.00000,0.00000,2",
+		"format":  "png",
+	}
+
+	tj := FromMetadata(metadata, "http://localhost/test/{z}/{x}/{y}.png")
+
+	if tj.Name != "test" || tj.Format != "png" {
+		t.Errorf("name or format not expected value: %v", tj)
+	}
+	if tj.MinZoom != 2 || tj.MaxZoom != 10 {
+		t.Errorf("zoom levels (%v, %v) not expected value: (2, 10)", tj.MinZoom, tj.MaxZoom)
+	}
+	expectedBounds := []float64{-10, -20, 10, 20}
+	if len(tj.Bounds) != 4 {
+		t.Fatalf("bounds %v not expected value: %v", tj.Bounds, expectedBounds)
+	}
+	for i, expected := range expectedBounds {
+		if tj.Bounds[i] != expected {
+			t.Errorf("bounds %v not expected value: %v", tj.Bounds, expectedBounds)
+		}
+	}
+	if len(tj.Center) != 3 || tj.Center[2] != 2 {
+		t.Errorf("center %v not expected value", tj.Center)
+	}
+	if len(tj.Tiles) != 1 {
+		t.Errorf("tiles %v not expected value", tj.Tiles)
+	}
+}
+
+func TestFromMetadataEncoding(t *testing.T) {
+	tj := FromMetadata(map[string]string{"encoding": "terrarium"})
+	if tj.Encoding != "terrarium" {
+		t.Errorf("encoding %v not expected value: terrarium", tj.Encoding)
+	}
+}
+
+func TestFromMetadataTileMatrixSet(t *testing.T) {
+	tj := FromMetadata(map[string]string{"tile_matrix_set": "WorldCRS84Quad", "crs": "OGC:CRS84"})
+	if tj.TileMatrixSet != "WorldCRS84Quad" || tj.CRS != "OGC:CRS84" {
+		t.Errorf("tile matrix set %v and crs %v not expected values", tj.TileMatrixSet, tj.CRS)
+	}
+}
+
+func TestFromMetadataInvalidBounds(t *testing.T) {
+	tj := FromMetadata(map[string]string{"bounds": "1,2,3"})
+	if tj.Bounds != nil {
+		t.Errorf("bounds %v should be omitted", tj.Bounds)
+	}
+}
@@ -3,6 +3,8 @@ package tiles
 import (
 	"fmt"
 	"math"
+	"strconv"
+	"strings"
 
 	"github.com/brendan-ward/rastertiler/affine"
 )
@@ -12,7 +14,8 @@ var ORIGIN = RE * math.Pi
 var CE float64 = 2.0 * ORIGIN
 var DEG2RAD float64 = math.Pi / 180.0
 
-// WebMercator tile, numbered starting from upper left
+// Tile of a TileMatrixSet, numbered starting from upper left; Zoom is the
+// index of the tile matrix
 type TileID struct {
 	Zoom uint8
 	X    uint32
@@ -23,6 +26,27 @@ func NewTileID(zoom uint8, x uint32, y uint32) *TileID {
 	return &TileID{zoom, x, y}
 }
 
+// Parse a tile from "z/x/y", e.g., "4/3/5"
+func ParseTileID(value string) (*TileID, error) {
+	parts := strings.Split(strings.TrimSpace(value), "/")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("tile must be 'z/x/y': %v", value)
+	}
+	zoom, err := strconv.ParseUint(parts[0], 10, 8)
+	if err != nil {
+		return nil, fmt.Errorf("invalid zoom of tile: %v", value)
+	}
+	x, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid x of tile: %v", value)
+	}
+	y, err := strconv.ParseUint(parts[2], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid y of tile: %v", value)
+	}
+	return NewTileID(uint8(zoom), uint32(x), uint32(y)), nil
+}
+
 func GeoToMercator(lon float64, lat float64) (x float64, y float64) {
 	// truncate incoming values to world bounds
 	lon = math.Min(math.Max(lon, -180), 180)
@@ -69,25 +93,10 @@ func GeoToTile(zoom uint8, x float64, y float64) *TileID {
 }
 
 // TileRange calculates the min tile x, min tile y, max tile x, max tile y tile
-// range for Mercator coordinates xmin, ymin, xmax, ymax at a given zoom level.
-// Assumes bounds have already been clipped to Mercator world bounds.
+// range for Mercator coordinates xmin, ymin, xmax, ymax at a given zoom level
+// of WebMercatorQuad
 func TileRange(zoom uint8, bounds *affine.Bounds) (*TileID, *TileID) {
-	z2 := 1 << zoom
-	zoomFactor := float64(z2)
-	origin := -ORIGIN
-	eps := 1.0e-11
-
-	xmin := math.Min(math.Max(math.Floor(((bounds.Xmin-origin)/CE)*zoomFactor), 0), zoomFactor-1)
-	// ymin isn't right yet, spilling over
-	ymin := math.Min(math.Max(math.Floor(((1.0-(((bounds.Ymin-origin)/CE)+eps))*zoomFactor)), 0), zoomFactor-1)
-	xmax := math.Min(math.Max(math.Floor((((bounds.Xmax-origin)/CE)-eps)*zoomFactor), 0), zoomFactor-1)
-	ymax := math.Min(math.Max(math.Floor((1.0-((bounds.Ymax-origin)/CE))*zoomFactor), 0), zoomFactor-1)
-
-	// tiles start in upper left, flip y values
-	minTile := &TileID{Zoom: zoom, X: uint32(xmin), Y: uint32(ymax)}
-	maxTile := &TileID{Zoom: zoom, X: uint32(xmax), Y: uint32(ymin)}
-
-	return minTile, maxTile
+	return WebMercatorQuad.TileRange(zoom, bounds)
 }
 
 func (t *TileID) String() string {
@@ -107,13 +116,7 @@ func (t *TileID) GeoBounds() *affine.Bounds {
 	return bounds
 }
 
+// Get Mercator bounds of tile in WebMercatorQuad
 func (t *TileID) MercatorBounds() *affine.Bounds {
-	z2 := 1 << t.Zoom
-	bounds := &affine.Bounds{Xmin: 0, Ymin: 0, Xmax: 0, Ymax: 0}
-	tileSize := CE / (float64)(z2)
-	bounds.Xmin = (float64)(t.X)*tileSize - CE/2.0
-	bounds.Xmax = bounds.Xmin + tileSize
-	bounds.Ymax = CE/2 - (float64)(t.Y)*tileSize
-	bounds.Ymin = bounds.Ymax - tileSize
-	return bounds
+	return WebMercatorQuad.Bounds(t)
 }
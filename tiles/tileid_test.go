@@ -0,0 +1,31 @@
+package tiles
+
+import "testing"
+
+func TestParseTileID(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected TileID
+	}{
+		{value: "0/0/0", expected: TileID{0, 0, 0}},
+		{value: "4/3/5", expected: TileID{4, 3, 5}},
+		{value: " 14/8192/5461 ", expected: TileID{14, 8192, 5461}},
+	}
+
+	for _, tc := range tests {
+		tile, err := ParseTileID(tc.value)
+		if err != nil {
+			t.Errorf("could not parse %q: %v", tc.value, err)
+			continue
+		}
+		if *tile != tc.expected {
+			t.Errorf("%q: %v not expected value: %v", tc.value, tile, tc.expected)
+		}
+	}
+
+	for _, value := range []string{"", "4/3", "4/3/5/1", "a/3/5", "4/-1/5", "4/3/5.png", "256/0/0"} {
+		if _, err := ParseTileID(value); err == nil {
+			t.Errorf("expected error for %q", value)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package tiles
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/brendan-ward/rastertiler/affine"
+)
+
+// maximum zoom level of the built-in tile matrix sets
+const maxQuadZoom = 24
+
+// TileMatrix is the grid of tiles at a single zoom level of a TileMatrixSet.
+// Coordinates are in the CRS of the TileMatrixSet, in x (easting or
+// longitude), y (northing or latitude) order.
+type TileMatrix struct {
+	ID           string
+	CellSize     float64 // size of a pixel in CRS units
+	OriginX      float64
+	OriginY      float64
+	BottomLeft   bool // true if origin is the lower left corner of the matrix, otherwise the upper left corner
+	TileWidth    int  // in pixels
+	TileHeight   int  // in pixels
+	MatrixWidth  uint32
+	MatrixHeight uint32
+}
+
+// Size of a tile in CRS units
+func (m *TileMatrix) tileSize() (width float64, height float64) {
+	return m.CellSize * float64(m.TileWidth), m.CellSize * float64(m.TileHeight)
+}
+
+// Upper left corner of the matrix
+func (m *TileMatrix) topLeft() (x float64, y float64) {
+	if m.BottomLeft {
+		_, height := m.tileSize()
+		return m.OriginX, m.OriginY + float64(m.MatrixHeight)*height
+	}
+	return m.OriginX, m.OriginY
+}
+
+// TileMatrixSet is a set of tile grids in a CRS, one for each zoom level
+// ordered from lowest to highest zoom.  Tiles are numbered starting from the
+// upper left of each grid regardless of the corner of origin of the grid.
+// See https://docs.ogc.org/is/17-083r4/17-083r4.html
+type TileMatrixSet struct {
+	ID       string
+	CRS      string // CRS in a form understood by GDAL, e.g., "EPSG:3857"
+	Matrices []TileMatrix
+}
+
+// WebMercatorQuad is the spherical Mercator tile grid used by most web maps,
+// with one tile at zoom 0
+var WebMercatorQuad = newQuadTileMatrixSet("WebMercatorQuad", "EPSG:3857", -ORIGIN, ORIGIN, CE, 1, 1)
+
+// WorldCRS84Quad is the geographic (longitude, latitude) tile grid with two
+// tiles at zoom 0
+var WorldCRS84Quad = newQuadTileMatrixSet("WorldCRS84Quad", "OGC:CRS84", -180, 90, 180, 2, 1)
+
+// TileMatrixSets are the built-in tile matrix sets, by ID
+var TileMatrixSets = map[string]*TileMatrixSet{
+	WebMercatorQuad.ID: WebMercatorQuad,
+	WorldCRS84Quad.ID:  WorldCRS84Quad,
+}
+
+// Create a tile matrix set of 256 pixel tiles where each zoom level has
+// twice the number of rows and columns of the previous level.  TileExtent is
+// the size of a tile at zoom 0 in CRS units.
+func newQuadTileMatrixSet(id string, crs string, originX float64, originY float64, tileExtent float64, matrixWidth uint32, matrixHeight uint32) *TileMatrixSet {
+	tms := &TileMatrixSet{
+		ID:       id,
+		CRS:      crs,
+		Matrices: make([]TileMatrix, maxQuadZoom+1),
+	}
+	for zoom := 0; zoom <= maxQuadZoom; zoom++ {
+		tms.Matrices[zoom] = TileMatrix{
+			ID:           fmt.Sprint(zoom),
+			CellSize:     tileExtent / float64(uint64(256)<<zoom),
+			OriginX:      originX,
+			OriginY:      originY,
+			TileWidth:    256,
+			TileHeight:   256,
+			MatrixWidth:  matrixWidth << zoom,
+			MatrixHeight: matrixHeight << zoom,
+		}
+	}
+	return tms
+}
+
+// tileMatrixSetJSON is an OGC TileMatrixSet 2.0 JSON document
+type tileMatrixSetJSON struct {
+	ID          string          `json:"id"`
+	CRS         json.RawMessage `json:"crs"`
+	OrderedAxes []string        `json:"orderedAxes"`
+	Matrices    []struct {
+		ID                   string        `json:"id"`
+		CellSize             float64       `json:"cellSize"`
+		CornerOfOrigin       string        `json:"cornerOfOrigin"`
+		PointOfOrigin        []float64     `json:"pointOfOrigin"`
+		TileWidth            int           `json:"tileWidth"`
+		TileHeight           int           `json:"tileHeight"`
+		MatrixWidth          uint32        `json:"matrixWidth"`
+		MatrixHeight         uint32        `json:"matrixHeight"`
+		VariableMatrixWidths []interface{} `json:"variableMatrixWidths"`
+	} `json:"tileMatrices"`
+}
+
+// Read a tile matrix set from an OGC TileMatrixSet 2.0 JSON file
+func ReadTileMatrixSet(filename string) (*TileMatrixSet, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTileMatrixSet(data)
+}
+
+// Parse a tile matrix set from OGC TileMatrixSet 2.0 JSON.  Tile matrices
+// must have square tiles and may not use variable matrix widths.
+func ParseTileMatrixSet(data []byte) (*TileMatrixSet, error) {
+	var doc tileMatrixSetJSON
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid tile matrix set: %v", err)
+	}
+
+	crs, err := parseCRS(doc.CRS)
+	if err != nil {
+		return nil, err
+	}
+	if len(doc.Matrices) == 0 {
+		return nil, fmt.Errorf("tile matrix set must have at least one tile matrix")
+	}
+	if len(doc.Matrices) > math.MaxUint8+1 {
+		return nil, fmt.Errorf("tile matrix set must have no more than %v tile matrices", math.MaxUint8+1)
+	}
+
+	// point of origin is in the axis order of the CRS
+	swapAxes := false
+	if len(doc.OrderedAxes) > 0 {
+		switch strings.ToLower(doc.OrderedAxes[0]) {
+		case "lat", "latitude", "n", "northing", "y":
+			swapAxes = true
+		}
+	}
+
+	tms := &TileMatrixSet{
+		ID:       doc.ID,
+		CRS:      crs,
+		Matrices: make([]TileMatrix, len(doc.Matrices)),
+	}
+	for i, m := range doc.Matrices {
+		if len(m.PointOfOrigin) != 2 {
+			return nil, fmt.Errorf("point of origin of tile matrix %v must have 2 coordinates", m.ID)
+		}
+		if m.CellSize <= 0 || m.TileWidth <= 0 || m.TileHeight <= 0 || m.MatrixWidth == 0 || m.MatrixHeight == 0 {
+			return nil, fmt.Errorf("cell size, tile size, and matrix size of tile matrix %v must be greater than 0", m.ID)
+		}
+		if m.TileWidth != m.TileHeight {
+			return nil, fmt.Errorf("tiles of tile matrix %v must be square", m.ID)
+		}
+		if len(m.VariableMatrixWidths) > 0 {
+			return nil, fmt.Errorf("variable matrix widths of tile matrix %v are not supported", m.ID)
+		}
+		if i > 0 && m.CellSize >= tms.Matrices[i-1].CellSize {
+			return nil, fmt.Errorf("tile matrices must be in descending order of cell size")
+		}
+
+		matrix := TileMatrix{
+			ID:           m.ID,
+			CellSize:     m.CellSize,
+			OriginX:      m.PointOfOrigin[0],
+			OriginY:      m.PointOfOrigin[1],
+			TileWidth:    m.TileWidth,
+			TileHeight:   m.TileHeight,
+			MatrixWidth:  m.MatrixWidth,
+			MatrixHeight: m.MatrixHeight,
+		}
+		if swapAxes {
+			matrix.OriginX, matrix.OriginY = matrix.OriginY, matrix.OriginX
+		}
+		switch m.CornerOfOrigin {
+		case "", "topLeft":
+		case "bottomLeft":
+			matrix.BottomLeft = true
+		default:
+			return nil, fmt.Errorf("corner of origin of tile matrix %v must be topLeft or bottomLeft: %v", m.ID, m.CornerOfOrigin)
+		}
+		tms.Matrices[i] = matrix
+	}
+
+	return tms, nil
+}
+
+// Parse the CRS of a tile matrix set, which is either a URI or an object
+// with a URI, to a form understood by GDAL
+func parseCRS(value json.RawMessage) (string, error) {
+	var uri string
+	if err := json.Unmarshal(value, &uri); err != nil {
+		var ref struct {
+			URI string `json:"uri"`
+		}
+		if err = json.Unmarshal(value, &ref); err != nil || ref.URI == "" {
+			return "", fmt.Errorf("CRS of tile matrix set must be a URI")
+		}
+		uri = ref.URI
+	}
+
+	// e.g., http://www.opengis.net/def/crs/EPSG/0/3857 or
+	// http://www.opengis.net/def/crs/OGC/1.3/CRS84
+	const prefix = "http://www.opengis.net/def/crs/"
+	if strings.HasPrefix(uri, prefix) {
+		parts := strings.Split(strings.TrimPrefix(uri, prefix), "/")
+		if len(parts) != 3 {
+			return "", fmt.Errorf("invalid CRS URI: %v", uri)
+		}
+		return parts[0] + ":" + parts[2], nil
+	}
+	if uri == "" {
+		return "", fmt.Errorf("tile matrix set must have a CRS")
+	}
+	return uri, nil
+}
+
+func (tms *TileMatrixSet) String() string {
+	return fmt.Sprintf("TileMatrixSet(%v, crs: %v, zoom levels: %v)", tms.ID, tms.CRS, len(tms.Matrices))
+}
+
+// Get the maximum zoom level
+func (tms *TileMatrixSet) MaxZoom() uint8 {
+	return uint8(len(tms.Matrices) - 1)
+}
+
+// Get the tile matrix of zoom, which must be no greater than MaxZoom()
+func (tms *TileMatrixSet) Matrix(zoom uint8) *TileMatrix {
+	return &tms.Matrices[zoom]
+}
+
+// IsQuadtree returns true if each tile is split into four tiles at the next
+// zoom level, as required to build tiles using a pyramid
+func (tms *TileMatrixSet) IsQuadtree() bool {
+	for i := 1; i < len(tms.Matrices); i++ {
+		prev := &tms.Matrices[i-1]
+		m := &tms.Matrices[i]
+		prevX, prevY := prev.topLeft()
+		x, y := m.topLeft()
+		if m.TileWidth != prev.TileWidth || m.MatrixWidth != 2*prev.MatrixWidth || m.MatrixHeight != 2*prev.MatrixHeight ||
+			!nearlyEqual(m.CellSize*2, prev.CellSize) || !nearlyEqual(x, prevX) || !nearlyEqual(y, prevY) {
+			return false
+		}
+	}
+	return true
+}
+
+func nearlyEqual(a float64, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(math.Abs(a), math.Abs(b))
+}
+
+// Get bounds of tile in the CRS of the tile matrix set
+func (tms *TileMatrixSet) Bounds(tile *TileID) *affine.Bounds {
+	m := tms.Matrix(tile.Zoom)
+	width, height := m.tileSize()
+	left, top := m.topLeft()
+
+	bounds := &affine.Bounds{}
+	bounds.Xmin = left + float64(tile.X)*width
+	bounds.Xmax = bounds.Xmin + width
+	bounds.Ymax = top - float64(tile.Y)*height
+	bounds.Ymin = bounds.Ymax - height
+	return bounds
+}
+
+// TileRange calculates the min tile x, min tile y, max tile x, max tile y
+// tile range of tiles that intersect bounds in the CRS of the tile matrix set
+// at a given zoom level.  Tiles are limited to the extent of the tile matrix.
+func (tms *TileMatrixSet) TileRange(zoom uint8, bounds *affine.Bounds) (*TileID, *TileID) {
+	m := tms.Matrix(zoom)
+	width, height := m.tileSize()
+	left, top := m.topLeft()
+	// tolerance for bounds that fall on tile edges, as a fraction of a tile
+	eps := 1e-9
+
+	clamp := func(value float64, size uint32) uint32 {
+		return uint32(math.Min(math.Max(value, 0), float64(size-1)))
+	}
+
+	xmin := clamp(math.Floor((bounds.Xmin-left)/width+eps), m.MatrixWidth)
+	xmax := clamp(math.Ceil((bounds.Xmax-left)/width-eps)-1, m.MatrixWidth)
+	ymin := clamp(math.Floor((top-bounds.Ymax)/height+eps), m.MatrixHeight)
+	ymax := clamp(math.Ceil((top-bounds.Ymin)/height-eps)-1, m.MatrixHeight)
+
+	return &TileID{Zoom: zoom, X: xmin, Y: ymin}, &TileID{Zoom: zoom, X: xmax, Y: ymax}
+}
+
+// Contains returns true if tile is within the tile matrix set
+func (tms *TileMatrixSet) Contains(tile *TileID) bool {
+	if int(tile.Zoom) >= len(tms.Matrices) {
+		return false
+	}
+	m := tms.Matrix(tile.Zoom)
+	return tile.X < m.MatrixWidth && tile.Y < m.MatrixHeight
+}
@@ -0,0 +1,122 @@
+package tiles
+
+import (
+	"math"
+	"testing"
+
+	"github.com/brendan-ward/rastertiler/affine"
+)
+
+func boundsNearlyEqual(a *affine.Bounds, b *affine.Bounds) bool {
+	tolerance := 1e-6
+	return math.Abs(a.Xmin-b.Xmin) < tolerance && math.Abs(a.Ymin-b.Ymin) < tolerance &&
+		math.Abs(a.Xmax-b.Xmax) < tolerance && math.Abs(a.Ymax-b.Ymax) < tolerance
+}
+
+func TestTileMatrixSetBounds(t *testing.T) {
+	tests := []struct {
+		tms      *TileMatrixSet
+		tile     *TileID
+		expected affine.Bounds
+	}{
+		{tms: WebMercatorQuad, tile: NewTileID(0, 0, 0), expected: affine.Bounds{Xmin: -ORIGIN, Ymin: -ORIGIN, Xmax: ORIGIN, Ymax: ORIGIN}},
+		{tms: WebMercatorQuad, tile: NewTileID(1, 1, 0), expected: affine.Bounds{Xmin: 0, Ymin: 0, Xmax: ORIGIN, Ymax: ORIGIN}},
+		{tms: WorldCRS84Quad, tile: NewTileID(0, 0, 0), expected: affine.Bounds{Xmin: -180, Ymin: -90, Xmax: 0, Ymax: 90}},
+		{tms: WorldCRS84Quad, tile: NewTileID(0, 1, 0), expected: affine.Bounds{Xmin: 0, Ymin: -90, Xmax: 180, Ymax: 90}},
+		{tms: WorldCRS84Quad, tile: NewTileID(2, 3, 1), expected: affine.Bounds{Xmin: -45, Ymin: 0, Xmax: 0, Ymax: 45}},
+	}
+
+	for _, tc := range tests {
+		bounds := tc.tms.Bounds(tc.tile)
+		if !boundsNearlyEqual(bounds, &tc.expected) {
+			t.Errorf("%v bounds of %v: %v not expected value: %v", tc.tms.ID, tc.tile, bounds, tc.expected)
+		}
+	}
+}
+
+func TestTileMatrixSetTileRange(t *testing.T) {
+	tests := []struct {
+		tms      *TileMatrixSet
+		zoom     uint8
+		bounds   affine.Bounds
+		expected [4]uint32
+	}{
+		// bounds on tile edges only include tiles they intersect
+		{tms: WebMercatorQuad, zoom: 1, bounds: affine.Bounds{Xmin: 0, Ymin: 0, Xmax: ORIGIN, Ymax: ORIGIN}, expected: [4]uint32{1, 0, 1, 0}},
+		{tms: WebMercatorQuad, zoom: 2, bounds: affine.Bounds{Xmin: -1, Ymin: -1, Xmax: 1, Ymax: 1}, expected: [4]uint32{1, 1, 2, 2}},
+		// bounds outside the tile matrix are limited to the extent of the matrix
+		{tms: WebMercatorQuad, zoom: 1, bounds: affine.Bounds{Xmin: -2 * ORIGIN, Ymin: math.Inf(-1), Xmax: 2 * ORIGIN, Ymax: math.Inf(1)}, expected: [4]uint32{0, 0, 1, 1}},
+		{tms: WorldCRS84Quad, zoom: 0, bounds: affine.Bounds{Xmin: -10, Ymin: -10, Xmax: 10, Ymax: 10}, expected: [4]uint32{0, 0, 1, 0}},
+		{tms: WorldCRS84Quad, zoom: 1, bounds: affine.Bounds{Xmin: 100, Ymin: 10, Xmax: 120, Ymax: 20}, expected: [4]uint32{3, 0, 3, 0}},
+	}
+
+	for _, tc := range tests {
+		minTile, maxTile := tc.tms.TileRange(tc.zoom, &tc.bounds)
+		actual := [4]uint32{minTile.X, minTile.Y, maxTile.X, maxTile.Y}
+		if actual != tc.expected {
+			t.Errorf("%v tile range of %v at zoom %v: %v not expected value: %v", tc.tms.ID, tc.bounds, tc.zoom, actual, tc.expected)
+		}
+	}
+}
+
+func TestTileMatrixSetIsQuadtree(t *testing.T) {
+	if !WebMercatorQuad.IsQuadtree() || !WorldCRS84Quad.IsQuadtree() {
+		t.Error("built-in tile matrix sets should be quadtrees")
+	}
+}
+
+func TestParseTileMatrixSet(t *testing.T) {
+	// bottom left origin in northing, easting axis order
+	data := []byte(`{
+		"id": "Grid",
+		"crs": {"uri": "http://www.opengis.net/def/crs/EPSG/0/2193"},
+		"orderedAxes": ["N", "E"],
+		"tileMatrices": [
+			{"id": "0", "scaleDenominator": 1000, "cellSize": 100, "cornerOfOrigin": "bottomLeft", "pointOfOrigin": [5000000, 1000000], "tileWidth": 256, "tileHeight": 256, "matrixWidth": 2, "matrixHeight": 3},
+			{"id": "1", "scaleDenominator": 500, "cellSize": 50, "cornerOfOrigin": "bottomLeft", "pointOfOrigin": [5000000, 1000000], "tileWidth": 256, "tileHeight": 256, "matrixWidth": 4, "matrixHeight": 6}
+		]
+	}`)
+
+	tms, err := ParseTileMatrixSet(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tms.ID != "Grid" || tms.CRS != "EPSG:2193" || tms.MaxZoom() != 1 {
+		t.Errorf("%v not expected value", tms)
+	}
+	if !tms.IsQuadtree() {
+		t.Errorf("%v should be a quadtree", tms)
+	}
+
+	// tiles are numbered from upper left
+	expected := affine.Bounds{Xmin: 1000000, Ymin: 5000000 + 2*25600, Xmax: 1000000 + 25600, Ymax: 5000000 + 3*25600}
+	if bounds := tms.Bounds(NewTileID(0, 0, 0)); !boundsNearlyEqual(bounds, &expected) {
+		t.Errorf("bounds %v not expected value: %v", bounds, expected)
+	}
+
+	if !tms.Contains(NewTileID(1, 3, 5)) || tms.Contains(NewTileID(1, 4, 0)) || tms.Contains(NewTileID(2, 0, 0)) {
+		t.Error("tile matrix set does not contain expected tiles")
+	}
+
+	tms, err = ParseTileMatrixSet([]byte(`{"id": "CRS84", "crs": "http://www.opengis.net/def/crs/OGC/1.3/CRS84", "tileMatrices": [{"id": "0", "cellSize": 1, "pointOfOrigin": [-180, 90], "tileWidth": 256, "tileHeight": 256, "matrixWidth": 2, "matrixHeight": 1}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tms.CRS != "OGC:CRS84" {
+		t.Errorf("CRS %v not expected value: OGC:CRS84", tms.CRS)
+	}
+}
+
+func TestParseTileMatrixSetInvalid(t *testing.T) {
+	for _, data := range []string{
+		`{"id": "NoCRS", "tileMatrices": [{"id": "0", "cellSize": 1, "pointOfOrigin": [0, 0], "tileWidth": 256, "tileHeight": 256, "matrixWidth": 1, "matrixHeight": 1}]}`,
+		`{"id": "NoMatrices", "crs": "EPSG:3857", "tileMatrices": []}`,
+		`{"id": "NotSquare", "crs": "EPSG:3857", "tileMatrices": [{"id": "0", "cellSize": 1, "pointOfOrigin": [0, 0], "tileWidth": 256, "tileHeight": 512, "matrixWidth": 1, "matrixHeight": 1}]}`,
+		`{"id": "Corner", "crs": "EPSG:3857", "tileMatrices": [{"id": "0", "cellSize": 1, "cornerOfOrigin": "center", "pointOfOrigin": [0, 0], "tileWidth": 256, "tileHeight": 256, "matrixWidth": 1, "matrixHeight": 1}]}`,
+		`{"id": "Order", "crs": "EPSG:3857", "tileMatrices": [{"id": "0", "cellSize": 1, "pointOfOrigin": [0, 0], "tileWidth": 256, "tileHeight": 256, "matrixWidth": 1, "matrixHeight": 1}, {"id": "1", "cellSize": 2, "pointOfOrigin": [0, 0], "tileWidth": 256, "tileHeight": 256, "matrixWidth": 1, "matrixHeight": 1}]}`,
+	} {
+		if _, err := ParseTileMatrixSet([]byte(data)); err == nil {
+			t.Errorf("%v did not raise error", data)
+		}
+	}
+}
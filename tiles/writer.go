@@ -0,0 +1,27 @@
+package tiles
+
+import (
+	"github.com/brendan-ward/rastertiler/affine"
+)
+
+// TileWriter writes tiles and metadata to a tileset output, such as an
+// MBTiles file or a directory.  WriteTile must be safe to call from multiple
+// goroutines.
+type TileWriter interface {
+	// WriteMetadata writes metadata, including format of tiles, e.g., "png"
+	WriteMetadata(name string, description string, attribution string, minZoom uint8, maxZoom uint8, bounds *affine.Bounds, format string) error
+	WriteMetadataItem(key string, value interface{}) error
+	WriteTile(tile *TileID, data []byte) error
+	// Finalize completes the tileset after all tiles have been written
+	Finalize() error
+	Close()
+}
+
+// UpdatableTileWriter is a TileWriter for an existing tileset, which can be
+// resumed or updated in place
+type UpdatableTileWriter interface {
+	TileWriter
+	ReadMetadata() (map[string]string, error)
+	ReadTileIDs() (map[TileID]bool, error)
+	DeleteTile(tile *TileID) error
+}